@@ -2,10 +2,12 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func openTargetFile(filename string) (io.WriteCloser, error) {
@@ -26,13 +28,35 @@ func openSourceFile(filename string) (io.ReadCloser, error) {
 
 func chooseRendererBaseOnOutfile(filename string) (Renderer, error) {
 	ext := filepath.Ext(filename)
-	if ext == ".png" {
+	if renderer, err := chooseRendererForFormat(strings.TrimPrefix(ext, ".")); err == nil {
+		return renderer, nil
+	}
+
+	return nil, errors.New("Unsupported extension: " + filename)
+}
+
+func chooseRendererForFormat(format string) (Renderer, error) {
+	switch strings.ToLower(format) {
+	case "png":
 		return PngRenderer, nil
-	} else if ext == ".svg" {
+	case "svg":
 		return SvgRenderer, nil
+	case "html":
+		return HtmlRenderer, nil
 	}
 
-	return nil, errors.New("Unsupported extension: " + filename)
+	return nil, errors.New("Unsupported format: " + format)
+}
+
+// Returns the filename of a page.  If there is more than one page, the page number
+// is added before the extension (e.g. "out-2.svg").
+func pageFilename(filename string, page int, pages int) string {
+	if pages <= 1 {
+		return filename
+	}
+
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), page, ext)
 }
 
 type nopWriteCloser struct {
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -18,14 +19,67 @@ import (
 var flagOut = flag.String("o", "", "Output file")
 
 // The style to use
-var flagStyle = flag.String("s", "default", "The style to use")
+var flagStyle = flag.String("s", "default", "The style to use, either a built-in style or a style file")
 
 // Generate an embedded SVG file
 var flagEmbedded = flag.Bool("e", false, "Generate an embedded SVG file")
 
+// The font files of the diagram
+var flagFont = flag.String("font", "", "Font file of the diagram, or comma separated part=file for the title, message or note fonts")
+
+// The fallback fonts
+var flagFallbackFont = flag.String("fallback-font", "", "Comma separated font files for the characters missing from the fonts of the diagram")
+
+// Leave the background transparent
+var flagTransparent = flag.Bool("transparent", false, "Leave the background of the diagram transparent")
+
+// Write the styles of the SVG elements to a style sheet
+var flagCSS = flag.Bool("css", false, "Write the styles of the SVG to a style sheet so it can be restyled with CSS")
+
+// The URL of the fonts
+var flagFontURL = flag.String("font-url", "", "URL of the fonts used by the SVG, or 'none' (default is to embed the fonts)")
+
 // Setup a watcher to regenerate the file when changed
 var flagWatch = flag.Bool("w", false, "Watch for changes")
 
+// Reorder actors to reduce the span of the actions
+var flagAutoOrder = flag.Bool("autoorder", false, "Reorder participants to reduce the span of messages")
+
+// The view to render
+var flagView = flag.String("view", "", "The view declared in the diagram to render")
+
+// Participants to hide
+var flagHide = flag.String("hide", "", "Comma separated participants to hide (use Name=Proxy to reroute messages)")
+
+// Composite participants to collapse
+var flagCollapse = flag.String("collapse", "", "Comma separated composite participants to collapse")
+
+// The range of items to render
+var flagFromLabel = flag.String("from", "", "Only render the items after this label")
+var flagToLabel = flag.String("to", "", "Only render the items before this label")
+
+// The maximum height of each page
+var flagPageHeight = flag.Int("page-height", 0, "Break the diagram into pages no taller than this height (requires an output file)")
+
+// The features to enable
+var flagFeatures featureList
+
+func init() {
+	flag.Var(&flagFeatures, "D", "Enable a feature (can be repeated or comma separated)")
+}
+
+// A list of features, which can be set multiple times on the command line
+type featureList []string
+
+func (fl *featureList) String() string {
+	return strings.Join(*fl, ",")
+}
+
+func (fl *featureList) Set(value string) error {
+	*fl = append(*fl, seqdiagram.SplitList(value)...)
+	return nil
+}
+
 // Die with error
 func die(msg string) {
 	fmt.Fprintf(os.Stderr, "goseq: %s\n", msg)
@@ -33,17 +87,80 @@ func die(msg string) {
 }
 
 // Construct and build image options based on the current configuration
-func buildImageOptions() *seqdiagram.ImageOptions {
+func buildImageOptions() (*seqdiagram.ImageOptions, error) {
 	// Work out the style
-	style := seqdiagram.DefaultStyle
-	if altStyle, hasStyle := seqdiagram.StyleNames[*flagStyle]; hasStyle {
-		style = altStyle
+	style, err := lookupStyle(*flagStyle, "")
+	if err != nil {
+		return nil, err
 	}
 
 	return &seqdiagram.ImageOptions{
-		Style:    style,
-		Embedded: *flagEmbedded,
+		Style:        style,
+		Embedded:     *flagEmbedded,
+		Features:     append([]string(nil), flagFeatures...),
+		AutoOrder:    *flagAutoOrder,
+		View:         *flagView,
+		HiddenActors: seqdiagram.ParseHiddenActors(*flagHide),
+		Collapse:     seqdiagram.SplitList(*flagCollapse),
+		FromLabel:    *flagFromLabel,
+		ToLabel:      *flagToLabel,
+		PageHeight:   *flagPageHeight,
+		Transparent:  *flagTransparent,
+		StyleClasses: *flagCSS,
+		FontURL:      *flagFontURL,
+	}, nil
+}
+
+// Applies the fonts set on the command line to a style.  This is done after the processing
+// instructions of a diagram, so that the fonts also apply to a style chosen by the diagram.
+func applyFontFlags(style *seqdiagram.DiagramStyles) (*seqdiagram.DiagramStyles, error) {
+	if *flagFont != "" {
+		var err error
+		if style, err = style.WithFontFiles(parseFontFlag(*flagFont)); err != nil {
+			return nil, err
+		}
+	}
+
+	if *flagFallbackFont != "" {
+		style = style.Copy()
+		style.FallbackFonts = append(append([]string(nil), style.FallbackFonts...), seqdiagram.SplitList(*flagFallbackFont)...)
 	}
+
+	return style, nil
+}
+
+// Parses the value of the font flag into the font files of the parts of the diagram.  A
+// file without a part is the font of the whole diagram.
+func parseFontFlag(value string) map[string]string {
+	fontFiles := make(map[string]string)
+	for _, item := range seqdiagram.SplitList(value) {
+		if kv := strings.SplitN(item, "=", 2); len(kv) == 2 {
+			fontFiles[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		} else {
+			fontFiles["diagram"] = item
+		}
+	}
+	return fontFiles
+}
+
+// Returns a built-in style by name.  If there is no such style, the style is loaded
+// from the style file with the name, relative to a directory if it is not blank.
+func lookupStyle(name string, dir string) (*seqdiagram.DiagramStyles, error) {
+	if style, hasStyle := seqdiagram.StyleNames[name]; hasStyle {
+		return style, nil
+	}
+
+	if (dir != "") && !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+
+	style, err := seqdiagram.LoadStyleFile(name)
+	if os.IsNotExist(err) {
+		return nil, errors.New("Unrecognised style: " + name)
+	} else if err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+	return style, nil
 }
 
 // Processes a md file
@@ -58,7 +175,7 @@ func processMdFile(inFilename string, outFilename string, renderer Renderer) err
 
 	mf := &MarkdownFilter{srcFile, targetFile, func(codeblock string, output io.Writer) error {
 		fmt.Fprint(output, codeblock)
-		err := processSeqDiagram(strings.NewReader(codeblock), inFilename, "/dev/null", nil)
+		err := processSeqDiagram(strings.NewReader(codeblock), inFilename, "", nil)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "goseq: %s:embedded block - %s\n", inFilename, err.Error())
 		}
@@ -86,24 +203,43 @@ func processSeqDiagram(infile io.Reader, inFilename string, outFilename string,
 	}
 
 	// Image options
-	imageOptions := buildImageOptions()
+	imageOptions, err := buildImageOptions()
+	if err != nil {
+		return err
+	}
+	settings := &diagramSettings{OutFilename: outFilename, Dir: filepath.Dir(inFilename)}
 
-	// If there's a process instruction, use it as the target of the diagram
-	// TODO: be a little smarter with the process instructions
+	// Apply the process instructions to the options of this diagram
 	for _, pr := range diagram.ProcessingInstructions {
 		if pr.Prefix == "goseq" {
-			outFilename = pr.Value
+			if err := applyProcessingInstruction(pr.Value, imageOptions, settings); err != nil {
+				return err
+			}
 		}
 	}
 
-	if renderer == nil {
-		renderer, err = chooseRendererBaseOnOutfile(outFilename)
+	// Diagrams embedded within other files are only rendered if they have an output file
+	if (renderer == nil) && (settings.OutFilename == "") {
+		return nil
+	}
+
+	if imageOptions.Style, err = applyFontFlags(imageOptions.Style); err != nil {
+		return err
+	}
+
+	if settings.Format != "" {
+		renderer, err = chooseRendererForFormat(settings.Format)
+		if err != nil {
+			return err
+		}
+	} else if (renderer == nil) || (settings.OutFilename != outFilename) {
+		renderer, err = chooseRendererBaseOnOutfile(settings.OutFilename)
 		if err != nil {
 			return err
 		}
 	}
 
-	err = renderer(diagram, imageOptions, outFilename)
+	err = renderer(diagram, imageOptions, settings.OutFilename)
 	if err != nil {
 		return err
 	}
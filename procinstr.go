@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lmika/goseq/seqdiagram"
+)
+
+// Settings for a single diagram, as set by a "#!goseq" processing instruction.
+// The instruction consists of a list of space separated "key=value" pairs:
+//
+//      #!goseq style=small format=png out=img/flow.png embedded=true scale=2
+//
+// For backwards compatibility, a value without any of the option keys is used as the
+// output filename, which may contain spaces and "=".
+type diagramSettings struct {
+	// The output filename
+	OutFilename string
+
+	// The output format.  If blank, the format is determined from the output filename
+	Format string
+
+	// The directory of the diagram file.  Style files named by the instruction are
+	// relative to this directory.
+	Dir string
+}
+
+// Applies the value of a "#!goseq" processing instruction to the image options and
+// diagram settings.
+func applyProcessingInstruction(value string, imageOptions *seqdiagram.ImageOptions, settings *diagramSettings) error {
+	if !hasProcessingInstructionOption(value) {
+		settings.OutFilename = strings.TrimSpace(value)
+		return nil
+	}
+
+	for _, field := range strings.Fields(value) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 1 {
+			settings.OutFilename = field
+			continue
+		}
+
+		key, val := strings.ToLower(kv[0]), kv[1]
+		switch key {
+		case "out":
+			settings.OutFilename = val
+		case "format":
+			settings.Format = val
+		case "style":
+			style, err := lookupStyle(val, settings.Dir)
+			if err != nil {
+				return err
+			}
+			imageOptions.Style = style
+		case "embedded":
+			embedded, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("Invalid value for embedded: %s", val)
+			}
+			imageOptions.Embedded = embedded
+		case "transparent":
+			transparent, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("Invalid value for transparent: %s", val)
+			}
+			imageOptions.Transparent = transparent
+		case "css":
+			styleClasses, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("Invalid value for css: %s", val)
+			}
+			imageOptions.StyleClasses = styleClasses
+		case "font-url":
+			imageOptions.FontURL = val
+		case "autoorder":
+			autoOrder, err := strconv.ParseBool(val)
+			if err != nil {
+				return fmt.Errorf("Invalid value for autoorder: %s", val)
+			}
+			imageOptions.AutoOrder = autoOrder
+		case "view":
+			imageOptions.View = val
+		case "hide":
+			if imageOptions.HiddenActors == nil {
+				imageOptions.HiddenActors = make(map[string]string)
+			}
+			for name, proxy := range seqdiagram.ParseHiddenActors(val) {
+				imageOptions.HiddenActors[name] = proxy
+			}
+		case "from":
+			imageOptions.FromLabel = val
+		case "to":
+			imageOptions.ToLabel = val
+		case "collapse":
+			imageOptions.Collapse = append(imageOptions.Collapse, seqdiagram.SplitList(val)...)
+		case "features":
+			imageOptions.Features = append(imageOptions.Features, seqdiagram.SplitList(val)...)
+		case "page-height":
+			pageHeight, err := strconv.Atoi(val)
+			if (err != nil) || (pageHeight < 0) {
+				return fmt.Errorf("Invalid value for page-height: %s", val)
+			}
+			imageOptions.PageHeight = pageHeight
+		case "scale":
+			scale, err := strconv.ParseFloat(val, 64)
+			if (err != nil) || (scale <= 0) {
+				return fmt.Errorf("Invalid value for scale: %s", val)
+			}
+			imageOptions.Scale = scale
+		default:
+			return errors.New("Unrecognised processing instruction option: " + key)
+		}
+	}
+
+	return nil
+}
+
+// The keys of the options of a processing instruction
+var processingInstructionOptions = map[string]bool{
+	"out": true, "format": true, "style": true, "embedded": true, "transparent": true, "css": true,
+	"font-url": true, "autoorder": true, "view": true, "hide": true, "from": true, "to": true,
+	"collapse": true, "features": true, "page-height": true, "scale": true,
+}
+
+// Returns true if a field of the value of a processing instruction sets one of the options
+func hasProcessingInstructionOption(value string) bool {
+	for _, field := range strings.Fields(value) {
+		if kv := strings.SplitN(field, "=", 2); (len(kv) == 2) && processingInstructionOptions[strings.ToLower(kv[0])] {
+			return true
+		}
+	}
+	return false
+}
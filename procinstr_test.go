@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lmika/goseq/seqdiagram"
+	"github.com/seanpont/assert"
+)
+
+func TestProcessingInstructionLegacyFilename(t *testing.T) {
+	assert := assert.Assert(t)
+
+	opts := &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle}
+	settings := &diagramSettings{}
+
+	err := applyProcessingInstruction("out.svg", opts, settings)
+
+	assert.Equal(err, nil)
+	assert.Equal(settings.OutFilename, "out.svg")
+	assert.Equal(settings.Format, "")
+	assert.Equal(opts.Style, seqdiagram.DefaultStyle)
+}
+
+func TestProcessingInstructionLegacyFilenameWithSpacesAndEquals(t *testing.T) {
+	assert := assert.Assert(t)
+
+	for value, filename := range map[string]string{
+		" my flow.svg ":     "my flow.svg",
+		"flow=v2.svg":       "flow=v2.svg",
+		"img/a b=final.png": "img/a b=final.png",
+	} {
+		opts := &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle}
+		settings := &diagramSettings{}
+
+		err := applyProcessingInstruction(value, opts, settings)
+
+		assert.Equal(err, nil)
+		assert.Equal(settings.OutFilename, filename)
+		assert.Equal(opts.Style, seqdiagram.DefaultStyle)
+	}
+}
+
+func TestProcessingInstructionOptionKeys(t *testing.T) {
+	for key := range processingInstructionOptions {
+		err := applyProcessingInstruction(key+"=x", &seqdiagram.ImageOptions{}, &diagramSettings{})
+		if (err != nil) && strings.HasPrefix(err.Error(), "Unrecognised processing instruction option") {
+			t.Errorf("option %s is not applied: %v", key, err)
+		}
+	}
+}
+
+func TestProcessingInstructionOptions(t *testing.T) {
+	assert := assert.Assert(t)
+
+	opts := &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle}
+	settings := &diagramSettings{}
+
+	err := applyProcessingInstruction("style=small format=png out=img/flow.png embedded=true scale=2 page-height=600 font-url=fonts/", opts, settings)
+
+	assert.Equal(err, nil)
+	assert.Equal(settings.OutFilename, "img/flow.png")
+	assert.Equal(settings.Format, "png")
+	assert.Equal(opts.Style, seqdiagram.SmallStyle)
+	assert.Equal(opts.Embedded, true)
+	assert.Equal(opts.Scale, 2.0)
+	assert.Equal(opts.PageHeight, 600)
+	assert.Equal(opts.FontURL, "fonts/")
+}
+
+func TestProcessingInstructionBadOptions(t *testing.T) {
+	assert := assert.Assert(t)
+
+	for _, value := range []string{"style=huge", "embedded=maybe", "scale=-1", "page-height=tall", "style=small colour=red"} {
+		opts := &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle}
+		err := applyProcessingInstruction(value, opts, &diagramSettings{})
+
+		assert.Equal(err != nil, true)
+	}
+}
+
+func TestProcessingInstructionStyleFileRelativeToDiagram(t *testing.T) {
+	assert := assert.Assert(t)
+
+	opts := &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle}
+	err := applyProcessingInstruction("style=styles/house.json", opts, &diagramSettings{Dir: "tests"})
+
+	assert.Equal(err, nil)
+	assert.Equal(opts.Style != seqdiagram.DefaultStyle, true)
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"io"
 	"os"
 
 	"github.com/lmika/goseq/seqdiagram"
@@ -11,17 +13,27 @@ import (
 // (which is up to the renderer).
 type Renderer func(diagram *seqdiagram.Diagram, opts *seqdiagram.ImageOptions, target string) error
 
-// The default renderer: write the diagram to SVG
+// The default renderer: write the diagram to SVG.  Each page of the diagram is
+// written to a separate file.  Diagrams written to stdout are not broken into pages.
 func SvgRenderer(diagram *seqdiagram.Diagram, opts *seqdiagram.ImageOptions, target string) error {
-	if target != "" {
-		file, err := os.Create(target)
-		if err != nil {
-			return err
-		}
-		defer file.Close()
-
-		return diagram.WriteSVGWithOptions(file, opts)
+	if (target == "") && (opts.PageHeight > 0) {
+		return errors.New("A page height can only be used when writing to an output file")
+	} else if target != "" {
+		return diagram.WriteSVGPages(opts, func(page int, pages int) (io.WriteCloser, error) {
+			return os.Create(pageFilename(target, page, pages))
+		})
 	} else {
 		return diagram.WriteSVGWithOptions(os.Stdout, opts)
 	}
 }
+
+// Write the diagram as an HTML page with headers which stay at the top while scrolling
+func HtmlRenderer(diagram *seqdiagram.Diagram, opts *seqdiagram.ImageOptions, target string) error {
+	file, err := openTargetFile(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return diagram.WriteHTMLWithOptions(file, opts)
+}
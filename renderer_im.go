@@ -7,6 +7,7 @@ package main
 
 import (
 	"bytes"
+	"io"
 
 	"github.com/lmika/goseq/seqdiagram"
 	"github.com/quirkey/magick"
@@ -17,17 +18,23 @@ func PngRenderer(diagram *seqdiagram.Diagram, opts *seqdiagram.ImageOptions, tar
 		target = "out.png"
 	}
 
-	svgbufr := new(bytes.Buffer)
-	err := diagram.WriteSVGWithOptions(svgbufr, opts)
-	if err != nil {
-		return err
-	}
+	return diagram.WriteSVGPages(opts, func(page int, pages int) (io.WriteCloser, error) {
+		return &pngPageWriter{target: pageFilename(target, page, pages)}, nil
+	})
+}
+
+// Buffers the SVG of a page and converts it to PNG when closed
+type pngPageWriter struct {
+	bytes.Buffer
+	target string
+}
 
-	img, err := magick.NewFromBlob(svgbufr.Bytes(), "svg")
+func (pw *pngPageWriter) Close() error {
+	img, err := magick.NewFromBlob(pw.Bytes(), "svg")
 	if err != nil {
 		return err
 	}
 	defer img.Destroy()
 
-	return img.ToFile(target)
+	return img.ToFile(pw.target)
 }
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lmika/goseq/seqdiagram"
+	"github.com/seanpont/assert"
+)
+
+func TestSvgRendererPageHeightToStdout(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram, err := seqdiagram.ParseDiagram(strings.NewReader("A->B: message\n"), "test.seq")
+	assert.Equal(err, nil)
+
+	err = SvgRenderer(diagram, &seqdiagram.ImageOptions{Style: seqdiagram.DefaultStyle, PageHeight: 600}, "")
+	assert.Equal(err != nil, true)
+}
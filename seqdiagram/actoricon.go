@@ -2,6 +2,9 @@ package seqdiagram
 
 import (
 	"errors"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/lmika/goseq/seqdiagram/graphbox"
 )
@@ -15,9 +18,25 @@ type ActorIcon interface {
 // Error returned if the icon cannot be found
 var EIconNotFound = errors.New("Icon not found")
 
+// The prefix of icon names which refer to SVG files
+const fileIconPrefix = "file:"
+
+// Icons registered using RegisterActorIcon
+var registeredIcons = make(map[string]ActorIcon)
+
 // Lookup an actor icon based on it's name.  If the actor icon cannot be
-// found, an EIconNotFound error is returned
+// found, an EIconNotFound error is returned.  Names starting with "file:" refer
+// to an SVG file to load the icon from.
 func LookupActorIcon(name string) (ActorIcon, error) {
+	if strings.HasPrefix(name, fileIconPrefix) {
+		return LoadSVGActorIcon(strings.TrimPrefix(name, fileIconPrefix))
+	}
+
+	// Lookup registered icons, which take precedence over the builtin icons
+	if registeredIcon, hasRegisteredIcon := registeredIcons[name]; hasRegisteredIcon {
+		return registeredIcon, nil
+	}
+
 	// Lookup builtin icons
 	if builtinIcon, hasBuiltinIcon := builtinIcons[name]; hasBuiltinIcon {
 		return builtinIcon, nil
@@ -26,6 +45,38 @@ func LookupActorIcon(name string) (ActorIcon, error) {
 	return nil, EIconNotFound
 }
 
+// RegisterActorIcon registers an icon under the given name, which can then be used
+// with the "icon" attribute.  This is not safe to call while diagrams are being parsed.
+func RegisterActorIcon(name string, icon ActorIcon) {
+	registeredIcons[name] = icon
+}
+
+// NewActorIcon returns an actor icon which draws the graphbox icon
+func NewActorIcon(icon graphbox.Icon) ActorIcon {
+	return &builtinActorIcon{icon}
+}
+
+// NewSVGActorIcon returns an actor icon which draws an SVG image.  Large images are
+// scaled down to the size of the built-in icons.
+func NewSVGActorIcon(r io.Reader) (ActorIcon, error) {
+	icon, err := graphbox.NewSVGIcon(r)
+	if err != nil {
+		return nil, err
+	}
+	return &builtinActorIcon{icon}, nil
+}
+
+// LoadSVGActorIcon returns an actor icon which draws the SVG image of a file
+func LoadSVGActorIcon(filename string) (ActorIcon, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewSVGActorIcon(file)
+}
+
 // A build-in actor icon
 type builtinActorIcon struct {
 	icon graphbox.Icon
@@ -37,6 +88,13 @@ func (bai *builtinActorIcon) graphboxIcon() graphbox.Icon {
 
 // The set of built-in icons
 var builtinIcons = map[string]ActorIcon{
-	"human":    &builtinActorIcon{graphbox.StickPersonIcon(1)},
-	"cylinder": &builtinActorIcon{graphbox.CylinderIcon(1)},
+	"human":       &builtinActorIcon{graphbox.StickPersonIcon(1)},
+	"cylinder":    &builtinActorIcon{graphbox.CylinderIcon(1)},
+	"boundary":    &builtinActorIcon{graphbox.BoundaryIcon(1)},
+	"control":     &builtinActorIcon{graphbox.ControlIcon(1)},
+	"entity":      &builtinActorIcon{graphbox.EntityIcon(1)},
+	"queue":       &builtinActorIcon{graphbox.QueueIcon(1)},
+	"collections": &builtinActorIcon{graphbox.CollectionsIcon(1)},
+	"component":   &builtinActorIcon{graphbox.ComponentIcon(1)},
+	"cloud":       &builtinActorIcon{graphbox.CloudIcon(1)},
 }
@@ -0,0 +1,57 @@
+package seqdiagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lmika/goseq/seqdiagram/graphbox"
+	"github.com/seanpont/assert"
+)
+
+func TestLookupBuiltinActorIcons(t *testing.T) {
+	assert := assert.Assert(t)
+
+	for _, name := range []string{"human", "cylinder", "boundary", "control", "entity", "queue",
+		"collections", "component", "cloud"} {
+
+		icon, err := LookupActorIcon(name)
+		assert.Equal(err, nil)
+		assert.Equal(icon != nil, true)
+	}
+
+	_, err := LookupActorIcon("robot")
+	assert.Equal(err, EIconNotFound)
+}
+
+func TestRegisteredActorIconsTakePrecedence(t *testing.T) {
+	assert := assert.Assert(t)
+
+	RegisterActorIcon("cloud", NewActorIcon(graphbox.QueueIcon(1)))
+	defer delete(registeredIcons, "cloud")
+
+	icon, err := LookupActorIcon("cloud")
+	assert.Equal(err, nil)
+	assert.Equal(icon.graphboxIcon(), graphbox.Icon(graphbox.QueueIcon(1)))
+}
+
+func TestActorIconsAndStereotypes(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant Q (icon=\"queue\")\n"+
+		"participant Orders (icon=\"control\", stereotype=\"service\")\nparticipant Plain\n")
+
+	q, orders, plain := diagram.Actors[0], diagram.Actors[1], diagram.Actors[2]
+	assert.Equal(q.Icon.graphboxIcon(), graphbox.Icon(graphbox.QueueIcon(1)))
+	assert.Equal(orders.Icon.graphboxIcon(), graphbox.Icon(graphbox.ControlIcon(1)))
+	assert.Equal(plain.Icon == nil, true)
+
+	assert.Equal(actorLabel(q), "Q")
+	assert.Equal(actorLabel(orders), "«service»\nOrders")
+}
+
+func TestUnknownActorIcon(t *testing.T) {
+	assert := assert.Assert(t)
+
+	_, err := ParseDiagram(strings.NewReader("participant R (icon=\"robot\")\n"), "test.seq")
+	assert.Equal(err != nil && strings.Contains(err.Error(), "error loading icon 'robot'"), true)
+}
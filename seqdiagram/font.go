@@ -2,6 +2,8 @@ package seqdiagram
 
 import (
 	"errors"
+	"path/filepath"
+	"sync"
 
 	"github.com/lmika/goseq/seqdiagram/graphbox"
 )
@@ -20,3 +22,64 @@ func loadInternalFont(fontName string) (*graphbox.TTFFont, error) {
 		return nil, errors.New("No such embedded font: " + fontName)
 	}
 }
+
+// The parts of the diagram which can be given their own font.  The diagram font is
+// used for every part of the diagram.
+var fontParts = map[string]func(styles *DiagramStyles, font graphbox.Font){
+	"diagram": (*DiagramStyles).setFont,
+	"title":   func(styles *DiagramStyles, font graphbox.Font) { styles.Title.Font = font },
+	"message": func(styles *DiagramStyles, font graphbox.Font) { styles.ActivityLine.Font = font },
+	"note":    func(styles *DiagramStyles, font graphbox.Font) { styles.NoteBox.Font = font },
+}
+
+// The fonts loaded from files, keyed by the absolute filename
+var loadedFonts = make(map[string]*graphbox.TTFFont)
+var loadedFontsMutex sync.Mutex
+
+// LoadFontFile loads a TrueType font file.  Each file is only loaded once, with later
+// loads returning the same font.
+func LoadFontFile(filename string) (*graphbox.TTFFont, error) {
+	absFilename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	loadedFontsMutex.Lock()
+	defer loadedFontsMutex.Unlock()
+
+	if font, isLoaded := loadedFonts[absFilename]; isLoaded {
+		return font, nil
+	}
+
+	font, err := graphbox.NewTTFFont(absFilename)
+	if err != nil {
+		return nil, errors.New("Cannot load font " + filename + ": " + err.Error())
+	}
+	loadedFonts[absFilename] = font
+	return font, nil
+}
+
+// WithFontFiles returns a copy of the styles with the fonts of parts of the diagram loaded
+// from font files.  The files are keyed by the part of the diagram: "diagram", "title",
+// "message" or "note".  The diagram font is set first so that the other parts can be
+// given a different font.
+func (ds *DiagramStyles) WithFontFiles(files map[string]string) (*DiagramStyles, error) {
+	parts := make([]string, 0, len(files))
+	for part := range files {
+		if _, isPart := fontParts[part]; !isPart {
+			return nil, errors.New("Unrecognised font part: " + part)
+		}
+		parts = append(parts, part)
+	}
+	sortDiagramFirst(parts)
+
+	styles := ds.Copy()
+	for _, part := range parts {
+		font, err := LoadFontFile(files[part])
+		if err != nil {
+			return nil, err
+		}
+		fontParts[part](styles, font)
+	}
+	return styles, nil
+}
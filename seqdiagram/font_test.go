@@ -0,0 +1,100 @@
+package seqdiagram
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestMeasureInternalFont(t *testing.T) {
+	assert := assert.Assert(t)
+
+	font, err := loadInternalFont(dejaVuSansFont)
+	assert.Equal(err, nil)
+
+	for _, test := range []struct {
+		text  string
+		width int
+	}{
+		{"A", 11},
+		{"V", 11},
+		{"AV", 21},
+		{"T", 10},
+		{"o", 10},
+		{"To", 17},
+		{"Hello, world", 95},
+	} {
+		w, h := font.Measure(test.text, 16)
+		assert.Equal(w, test.width)
+		assert.Equal(h, 16)
+	}
+
+	assert.Equal(font.Ascent("Hello", 16), 15)
+	assert.Equal(font.Ascent("Hello", 12), 12)
+}
+
+func TestMeasureInternalFontWithKerning(t *testing.T) {
+	assert := assert.Assert(t)
+
+	font, err := loadInternalFont(dejaVuSansFont)
+	assert.Equal(err, nil)
+
+	pairWidth, _ := font.Measure("AV", 64)
+	aWidth, _ := font.Measure("A", 64)
+	vWidth, _ := font.Measure("V", 64)
+	assert.Equal(pairWidth < aWidth+vWidth, true)
+}
+
+// Writes Go Regular to a font file within a temporary directory
+func writeFallbackFont(t *testing.T) string {
+	filename := filepath.Join(t.TempDir(), "Go-Regular.ttf")
+	if err := ioutil.WriteFile(filename, goregular.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestFallbackFontFamilies(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles := DefaultStyle.Copy()
+	styles.FallbackFonts = []string{writeFallbackFont(t)}
+	diagram := mustParseDiagram(t, "A->B: Hi\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{Style: styles, FontURL: "none"})
+	assert.Equal(strings.Contains(svg, "font-family:DejaVuSans,Go,sans-serif"), true)
+
+	svg = renderSVG(t, diagram, &ImageOptions{Style: styles})
+	assert.Equal(regexp.MustCompile(`font-family:DejaVuSans-[0-9a-f]{8},Go,sans-serif`).MatchString(svg), true)
+}
+
+func TestFallbackFontFamiliesOfMissingGlyphs(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles := DefaultStyle.Copy()
+	styles.FallbackFonts = []string{writeFallbackFont(t)}
+	// U+F800 is a private use character with a glyph in Go Regular, but not DejaVu Sans
+	diagram := mustParseDiagram(t, "A->B: Hi \uf800\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{Style: styles})
+	assert.Equal(regexp.MustCompile(`font-family:DejaVuSans-[0-9a-f]{8},Go-[0-9a-f]{8},sans-serif`).MatchString(svg), true)
+}
+
+func TestMissingFallbackFont(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles := DefaultStyle.Copy()
+	styles.FallbackFonts = []string{filepath.Join(t.TempDir(), "Missing.ttf")}
+
+	_, err := styles.withFallbackFonts()
+	assert.Equal(err != nil && strings.HasPrefix(err.Error(), "Cannot load font "), true)
+	assert.Equal(strings.Contains(err.Error(), "Missing.ttf"), true)
+
+	err = mustParseDiagram(t, "A->B: Hi\n").WriteSVGWithOptions(ioutil.Discard, &ImageOptions{Style: styles})
+	assert.Equal(err != nil, true)
+}
@@ -0,0 +1,44 @@
+package graphbox
+
+// ActivationStyle defines the style of an activation bar
+type ActivationStyle struct {
+	Width int
+
+	// The colour of the frame and the fill of the bar.  Blank colours are drawn black
+	// and white respectively.
+	Color     string
+	FillColor string
+}
+
+// Activation is a bar drawn over the lifeline of a participant while it handles a call.
+// It spans the rows between the call and its return.
+type Activation struct {
+	TR int
+
+	offset Point
+	style  ActivationStyle
+}
+
+// NewActivation creates a new activation bar.  The offset is the distance from the
+// lifeline and the row to start the bar, which is used to draw nested activations side
+// by side and to start the activations of self calls below the arrow.
+func NewActivation(toRow int, offset Point, style ActivationStyle) *Activation {
+	return &Activation{toRow, offset, style}
+}
+
+// Constraint returns the constraints of the graphics object
+func (a *Activation) Constraint(r, c int, applier ConstraintApplier) {
+}
+
+// Draw draws the graphics object
+func (a *Activation) Draw(ctx DrawContext, point Point) {
+	if toPoint, isPoint := ctx.PointAt(a.TR, ctx.C); isPoint {
+		x, fy := point.X-a.style.Width/2+a.offset.X, point.Y+a.offset.Y
+		if toPoint.Y <= fy {
+			return
+		}
+
+		ctx.Canvas.Rect(x, fy, a.style.Width, toPoint.Y-fy,
+			ctx.Style("stroke:"+colorOr(a.style.Color, "black")+";stroke-width:1px;fill:"+colorOr(a.style.FillColor, "white")+";"))
+	}
+}
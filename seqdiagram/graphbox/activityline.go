@@ -29,6 +29,11 @@ type ActivityLineStyle struct {
 	//ArrowHead       ActivityArrowHead
 	ArrowHead *ArrowHeadStyle
 	ArrowStem ActivityArrowStem
+
+	// The colour of the line and text, and of the rectangle behind the text.  Blank
+	// colours are drawn black and white respectively.
+	Color         string
+	KnockoutColor string
 }
 
 // Returns the text style
@@ -57,6 +62,7 @@ func NewActivityLine(toCol int, selfRef bool, text string, style ActivityLineSty
 	}
 
 	textBox := NewTextBox(style.Font, style.FontSize, textBoxAlign)
+	textBox.Color = style.Color
 	textBox.AddText(text)
 
 	brect := textBox.BoundingRect()
@@ -122,25 +128,27 @@ func (al *ActivityLine) Draw(ctx DrawContext, point Point) {
 
 // Draws the arrow stem
 func (al *ActivityLine) drawArrowStem(ctx DrawContext, fx, fy, tx, ty int) {
+	color := colorOr(al.style.Color, "black")
 	switch al.style.ArrowStem {
 	case SolidArrowStem:
-		ctx.Canvas.Line(fx, fy, tx, ty, "stroke:black;stroke-width:2px;")
+		ctx.Canvas.Line(fx, fy, tx, ty, ctx.Style("stroke:"+color+";stroke-width:2px;"))
 	case DashedArrowStem:
-		ctx.Canvas.Line(fx, fy, tx, ty, "stroke:black;stroke-dasharray:4,2;stroke-width:2px;")
+		ctx.Canvas.Line(fx, fy, tx, ty, ctx.Style("stroke:"+color+";stroke-dasharray:4,2;stroke-width:2px;"))
 	case ThickArrowStem:
-		ctx.Canvas.Line(fx, fy, tx, ty, "stroke:black;stroke-width:4px;")
+		ctx.Canvas.Line(fx, fy, tx, ty, ctx.Style("stroke:"+color+";stroke-width:4px;"))
 	}
 }
 
 // Draws the arrow stem path
 func (al *ActivityLine) drawArrowStemPath(ctx DrawContext, xs, ys []int) {
+	color := colorOr(al.style.Color, "black")
 	switch al.style.ArrowStem {
 	case SolidArrowStem:
-		ctx.Canvas.Polyline(xs, ys, "fill:none;stroke:black;stroke-width:2px;")
+		ctx.Canvas.Polyline(xs, ys, ctx.Style("fill:none;stroke:"+color+";stroke-width:2px;"))
 	case DashedArrowStem:
-		ctx.Canvas.Polyline(xs, ys, "fill:none;stroke:black;stroke-dasharray:4,2;stroke-width:2px;")
+		ctx.Canvas.Polyline(xs, ys, ctx.Style("fill:none;stroke:"+color+";stroke-dasharray:4,2;stroke-width:2px;"))
 	case ThickArrowStem:
-		ctx.Canvas.Polyline(xs, ys, "fill:none;stroke:black;stroke-width:4px;")
+		ctx.Canvas.Polyline(xs, ys, ctx.Style("fill:none;stroke:"+color+";stroke-width:4px;"))
 	}
 }
 
@@ -153,8 +161,9 @@ func (al *ActivityLine) renderMessage(ctx DrawContext, tx, ty int, anchorLeft bo
 
 	rect := al.textBoxRect.PositionAt(tx, ty, anchor)
 
-	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, "fill:white;stroke:white;")
-	al.textBox.Render(ctx.Canvas, tx, ty, anchor)
+	knockoutColor := colorOr(al.style.KnockoutColor, "white")
+	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, ctx.Style("fill:"+knockoutColor+";stroke:"+knockoutColor+";"))
+	al.textBox.Render(ctx, tx, ty, anchor)
 }
 
 // Draws the arrow head.
@@ -176,7 +185,15 @@ func (al *ActivityLine) drawArrow(ctx DrawContext, x, y int, isRight bool) {
 		ys[i] = y + oy
 	}
 
-	ctx.Canvas.Polyline(xs, ys, StyleFromString(headStyle.BaseStyle).ToStyle())
+	// The arrow head is drawn in the colour of the line, unless it is not filled
+	s := StyleFromString(headStyle.BaseStyle)
+	if al.style.Color != "" {
+		s.Set("stroke", al.style.Color)
+		if s["fill"] != "none" {
+			s.Set("fill", al.style.Color)
+		}
+	}
+	ctx.Canvas.Polyline(xs, ys, ctx.Style(s.ToStyle()))
 }
 
 // ArrowHeadStyle defines style information for the arrow heads
@@ -24,6 +24,9 @@ type ActorBoxStyle struct {
 	Margin    Point
 	Color     string
 	TextColor string
+
+	// The colour of the box.  Blank colours are drawn white.
+	FillColor string
 }
 
 // ActorBox represents an a actor
@@ -78,13 +81,13 @@ func (tr *ActorBox) Constraint(r, c int, applier ConstraintApplier) {
 
 func (r *ActorBox) Draw(ctx DrawContext, point Point) {
 	s := SvgStyle{}
-	s.Set("stroke", r.style.Color)
-	s.Set("fill", "white")
+	s.Set("stroke", colorOr(r.style.Color, "black"))
+	s.Set("fill", colorOr(r.style.FillColor, "white"))
 	s.Set("stroke-width", "2px")
 
 	centerX, centerY := point.X, point.Y
 
 	rect := r.frameRect.PositionAt(centerX, centerY, CenterGravity)
-	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, s.ToStyle())
-	r.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
+	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, ctx.Style(s.ToStyle()))
+	r.textBox.Render(ctx, centerX, centerY, CenterGravity)
 }
@@ -9,6 +9,11 @@ type ActorIconBoxStyle struct {
 	IconGap   int
 	Color     string
 	TextColor string
+
+	// The colour of the icon, and of the rectangles behind the icon and text.  Blank
+	// colours are drawn white.
+	FillColor     string
+	KnockoutColor string
 }
 
 // ActorIconBox represents an actor icon
@@ -52,6 +57,8 @@ func (tr *ActorIconBox) Constraint(r, c int, applier ConstraintApplier) {
 			applier.Apply(AddSizeConstraint{r, c, w / 2, w / 2, 0, 0})
 		}
 		applier.Apply(SizeConstraint{r, c, 0, 0, topH, bottomH})
+	} else {
+		applier.Apply(SizeConstraint{r, c, 0, 0, topH + tr.style.Margin.Y, bottomH})
 	}
 }
 
@@ -68,13 +75,14 @@ func (tr *ActorIconBox) Draw(ctx DrawContext, point Point) {
 
 	// Draw the icon
 	iconStyle := SvgStyle{}
-	iconStyle.Set("stroke", tr.style.Color)
-	iconStyle.Set("fill", "white")
+	iconStyle.Set("stroke", colorOr(tr.style.Color, "black"))
+	iconStyle.Set("fill", colorOr(tr.style.FillColor, "white"))
 	iconStyle.Set("stroke-width", "2px")
 
-	ctx.Canvas.Rect(rect.X, rect.Y-tr.style.IconGap, rect.W, rect.H+tr.style.IconGap, "stroke:white;fill:white;stroke-width:2px;")
-	tr.textBox.Render(ctx.Canvas, centerX, textY, NorthGravity)
+	knockoutColor := colorOr(tr.style.KnockoutColor, "white")
+	ctx.Canvas.Rect(rect.X, rect.Y-tr.style.IconGap, rect.W, rect.H+tr.style.IconGap, ctx.Style("stroke:"+knockoutColor+";fill:"+knockoutColor+";stroke-width:2px;"))
+	tr.textBox.Render(ctx, centerX, textY, NorthGravity)
 
-	ctx.Canvas.Rect(centerX-iconW/2, centerY-iconH/2, iconW, iconH, "stroke:white;fill:white;stroke-width:1px;")
+	ctx.Canvas.Rect(centerX-iconW/2, centerY-iconH/2, iconW, iconH, ctx.Style("stroke:"+knockoutColor+";fill:"+knockoutColor+";stroke-width:1px;"))
 	tr.Icon.Draw(ctx, iconX, iconY, &iconStyle)
 }
@@ -0,0 +1,21 @@
+package graphbox
+
+import (
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+func TestActorIconBoxFooterMargin(t *testing.T) {
+	assert := assert.Assert(t)
+
+	style := ActorIconBoxStyle{Font: testNoteStyle(t).Font, FontSize: 14, Padding: Point{4, 4}, Margin: Point{8, 12}, IconGap: 4}
+	footer := NewActorIconBox("DB", CylinderIcon(1), style, MiddleActorBox|BottomActorBox)
+	g, _ := drawTestItem(1, 0, footer)
+
+	// The icon of the footer is kept a margin below the row above it
+	_, iconH := CylinderIcon(1).Size()
+	above, _ := g.PointAt(0, 0)
+	row, _ := g.PointAt(1, 0)
+	assert.Equal(row.Y-above.Y, iconH/2+12)
+}
@@ -64,6 +64,11 @@ type DrawContext struct {
 	R, C    int
 }
 
+// Returns the attribute which applies a style to an element drawn on the canvas
+func (dc *DrawContext) Style(style string) string {
+	return dc.Graphic.styleAttr(style)
+}
+
 // Returns the outer rectangle of a particular cell
 func (dc *DrawContext) PointAt(r, c int) (Point, bool) {
 	return dc.Graphic.PointAt(r, c)
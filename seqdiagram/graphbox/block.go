@@ -13,6 +13,12 @@ type BlockStyle struct {
 	PrefixExtraWidth int
 	GapWidth         int
 	MidMargin        int
+
+	// The colour of the frame, the text, and the rectangles behind the text.  Blank
+	// colours are drawn black, black and white respectively.
+	Color         string
+	TextColor     string
+	KnockoutColor string
 }
 
 // A block
@@ -34,10 +40,12 @@ type Block struct {
 
 func NewBlock(toRow int, toCol int, marginMup int, isLast bool, prefix string, showPrefix bool, text string, style BlockStyle) *Block {
 	prefixTextBox := NewTextBox(style.Font, style.FontSize, MiddleTextAlign)
+	prefixTextBox.Color = style.TextColor
 	prefixTextBox.AddText(prefix)
 	prefixTextBoxRect := prefixTextBox.BoundingRect()
 
 	messageTextBox := NewTextBox(style.Font, style.FontSize, MiddleTextAlign)
+	messageTextBox.Color = style.TextColor
 	messageTextBox.AddText(text)
 	messageTextBoxRect := messageTextBox.BoundingRect()
 
@@ -86,7 +94,7 @@ func (block *Block) drawFrame(ctx DrawContext, fx, fy, tx, ty int) {
 	xs := []int{fx, fx, tx, tx}
 	ys := []int{ty, fy, fy, ty}
 
-	lineStyle := "stroke:black;stroke-dasharray:4,4;stroke-width:2px;fill:none;"
+	lineStyle := ctx.Style("stroke:" + colorOr(block.Style.Color, "black") + ";stroke-dasharray:4,4;stroke-width:2px;fill:none;")
 	if block.IsLast {
 		//ctx.Canvas.Rect(fx, fy, w, h, lineStyle)
 		ctx.Canvas.Polygon(xs, ys, lineStyle)
@@ -106,13 +114,13 @@ func (block *Block) drawText(ctx DrawContext, fx, fy int) {
 	mtr := block.messageTextBoxRect.BlowOut(block.Style.MessagePadding).PositionAt(fx+ptr.W, fy, NorthWestGravity)
 
 	if block.ShowMessage {
-		ctx.Canvas.Rect(mtr.X, mtr.Y, mtr.W+block.Style.GapWidth+block.Style.FontSize/2, mtr.H, "stroke:none;fill:white;")
-		block.messageTextBox.Render(ctx.Canvas, mtr.X+block.Style.GapWidth+block.Style.MessagePadding.X, mtr.Y+block.Style.MessagePadding.Y, NorthWestGravity)
+		ctx.Canvas.Rect(mtr.X, mtr.Y, mtr.W+block.Style.GapWidth+block.Style.FontSize/2, mtr.H, ctx.Style("stroke:none;fill:"+colorOr(block.Style.KnockoutColor, "white")+";"))
+		block.messageTextBox.Render(ctx, mtr.X+block.Style.GapWidth+block.Style.MessagePadding.X, mtr.Y+block.Style.MessagePadding.Y, NorthWestGravity)
 	}
 
 	if block.ShowPrefix {
 		block.drawPrefixFrame(ctx, ptr.X, ptr.Y, ptr.X+ptr.W, ptr.Y+ptr.H)
-		block.prefixTextBox.Render(ctx.Canvas, ptr.X+block.Style.TextPadding.X, ptr.Y+block.Style.TextPadding.Y, NorthWestGravity)
+		block.prefixTextBox.Render(ctx, ptr.X+block.Style.TextPadding.X, ptr.Y+block.Style.TextPadding.Y, NorthWestGravity)
 	}
 }
 
@@ -122,5 +130,5 @@ func (block *Block) drawPrefixFrame(ctx DrawContext, fx, fy, tx, ty int) {
 	xs := []int{fx, fx, tx - fold, tx, tx}
 	ys := []int{fy, ty, ty, ty - fold, fy}
 
-	ctx.Canvas.Polygon(xs, ys, "stroke:black;stroke-width:2px;fill:white;")
+	ctx.Canvas.Polygon(xs, ys, ctx.Style("stroke:"+colorOr(block.Style.Color, "black")+";stroke-width:2px;fill:"+colorOr(block.Style.KnockoutColor, "white")+";"))
 }
@@ -1,5 +1,9 @@
 package graphbox
 
+import (
+	"errors"
+)
+
 // DividerShape determines which shape to use for the divider
 type DividerShape int
 
@@ -20,6 +24,25 @@ const (
 	DSFullLine
 )
 
+// The names of the divider shapes
+var dividerShapeNames = map[string]DividerShape{
+	"fullrect":   DSFullRect,
+	"framedrect": DSFramedRect,
+	"spacerrect": DSSpacerRect,
+	"fullline":   DSFullLine,
+}
+
+// UnmarshalText sets the divider shape from its name, so that shapes can be named
+// in style files
+func (ds *DividerShape) UnmarshalText(text []byte) error {
+	shape, hasShape := dividerShapeNames[string(text)]
+	if !hasShape {
+		return errors.New("Unrecognised divider shape: " + string(text))
+	}
+	*ds = shape
+	return nil
+}
+
 // DividerStyle defines the style of the divider
 type DividerStyle struct {
 	Font        Font
@@ -29,6 +52,11 @@ type DividerStyle struct {
 	TextPadding Point
 	Overlap     int
 	Shape       DividerShape
+
+	// The colour of the lines and text, and of the shapes drawn behind the text.  Blank
+	// colours are drawn black and white respectively.
+	Color         string
+	KnockoutColor string
 }
 
 // Divider is a divider graphics object.  This spans the entire diagram.
@@ -48,6 +76,7 @@ type Divider struct {
 // NewDivider creates a new divider
 func NewDivider(toCol int, text string, style DividerStyle) *Divider {
 	textBox := NewTextBox(style.Font, style.FontSize, MiddleTextAlign)
+	textBox.Color = style.Color
 	textBox.AddText(text)
 	textBoxRect := textBox.BoundingRect()
 	marginRect := textBoxRect.BlowOut(style.Padding)
@@ -96,25 +125,29 @@ func (div *Divider) Draw(ctx DrawContext, point Point) {
 		borderRect := Rect{fx, fy - div.marginRect.H/2, tx - fx, div.marginRect.H}
 		textBoxRect := div.textBoxRect.PositionAt(centerX, centerY, CenterGravity).BlowOut(div.style.TextPadding)
 
+		color := colorOr(div.style.Color, "black")
+		knockoutColor := colorOr(div.style.KnockoutColor, "white")
+		knockoutStyle := ctx.Style("fill:" + knockoutColor + ";stroke:" + knockoutColor + ";")
+
 		// Draw the shape and text
 		switch div.style.Shape {
 		case DSFullRect:
-			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, "fill:white;stroke:white;")
-			div.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
+			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, knockoutStyle)
+			div.textBox.Render(ctx, centerX, centerY, CenterGravity)
 		case DSFramedRect:
-			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, "fill:white;stroke:black;stroke-width:2px")
-			div.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
+			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, ctx.Style("fill:"+knockoutColor+";stroke:"+color+";stroke-width:2px"))
+			div.textBox.Render(ctx, centerX, centerY, CenterGravity)
 		case DSSpacerRect:
-			ctx.Canvas.Rect(textBoxRect.X, textBoxRect.Y, textBoxRect.W, textBoxRect.H, "fill:white;stroke:white;")
-			div.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
+			ctx.Canvas.Rect(textBoxRect.X, textBoxRect.Y, textBoxRect.W, textBoxRect.H, knockoutStyle)
+			div.textBox.Render(ctx, centerX, centerY, CenterGravity)
 		case DSFullLine:
 			// Draw the rectangle for clearing the image
-			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, "fill:white;stroke:white;")
-			ctx.Canvas.Line(borderRect.X, centerY, borderRect.W, centerY, "fill:white;stroke:black;stroke-width:2px;") //stroke-dasharray:16,8")
+			ctx.Canvas.Rect(borderRect.X, borderRect.Y, borderRect.W, borderRect.H, knockoutStyle)
+			ctx.Canvas.Line(borderRect.X, centerY, borderRect.W, centerY, ctx.Style("fill:"+knockoutColor+";stroke:"+color+";stroke-width:2px;")) //stroke-dasharray:16,8")
 
 			if div.hasText {
-				ctx.Canvas.Rect(textBoxRect.X, textBoxRect.Y, textBoxRect.W, textBoxRect.H, "fill:white;stroke:white;")
-				div.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
+				ctx.Canvas.Rect(textBoxRect.X, textBoxRect.Y, textBoxRect.W, textBoxRect.H, knockoutStyle)
+				div.textBox.Render(ctx, centerX, centerY, CenterGravity)
 			}
 		}
 	}
@@ -0,0 +1,79 @@
+package graphbox
+
+// DurationLineStyle defines the style of a duration line
+type DurationLineStyle struct {
+	Font      Font
+	FontSize  int
+	Margin    Point
+	TextGap   int
+	ArrowSize int
+
+	// The colour of the line and text.  Blank colours are drawn black.
+	Color string
+}
+
+// DurationLine is a dimension line showing a duration constraint.  It is drawn in the
+// left margin of the diagram and spans the rows between two time points.
+type DurationLine struct {
+	TR int
+
+	offsetX     int
+	style       DurationLineStyle
+	textBox     *TextBox
+	textBoxRect Rect
+}
+
+// NewDurationLine creates a new duration line.  The offset is the distance from the
+// left of the column to draw the line, which is used to draw multiple duration lines
+// side by side.
+func NewDurationLine(toRow int, offsetX int, text string, style DurationLineStyle) *DurationLine {
+	textBox := NewTextBox(style.Font, style.FontSize, RightTextAlign)
+	textBox.Color = style.Color
+	textBox.AddText(text)
+
+	return &DurationLine{toRow, offsetX, style, textBox, textBox.BoundingRect()}
+}
+
+// Width returns the horizontal space required by the duration line and its text
+func (dl *DurationLine) Width() int {
+	return dl.textBoxRect.W + dl.style.TextGap + dl.style.ArrowSize + dl.style.Margin.X*2
+}
+
+// Constraint returns the constraints of the graphics object
+func (dl *DurationLine) Constraint(r, c int, applier ConstraintApplier) {
+	applier.Apply(AddSizeConstraint{r, c, 0, dl.Width(), 0, 0})
+	if dl.TR > r {
+		applier.Apply(TotalSizeConstraint{r, c, dl.TR, c, 0, dl.textBoxRect.H + dl.style.Margin.Y*2})
+	}
+}
+
+// Draw draws the graphics object
+func (dl *DurationLine) Draw(ctx DrawContext, point Point) {
+	if toPoint, isPoint := ctx.PointAt(dl.TR, ctx.C); isPoint {
+		fy, ty := point.Y, toPoint.Y
+		lineX := point.X + dl.offsetX + dl.style.Margin.X + dl.textBoxRect.W + dl.style.TextGap + dl.style.ArrowSize/2
+		arrowSize := dl.style.ArrowSize
+
+		color := colorOr(dl.style.Color, "black")
+		lineStyle := ctx.Style("stroke:" + color + ";stroke-width:1px;")
+		headStyle := ctx.Style("stroke:" + color + ";fill:" + color + ";stroke-width:1px;")
+
+		// The extension lines and dimension line
+		ctx.Canvas.Line(lineX-arrowSize, fy, lineX+arrowSize, fy, lineStyle)
+		ctx.Canvas.Line(lineX-arrowSize, ty, lineX+arrowSize, ty, lineStyle)
+		ctx.Canvas.Line(lineX, fy, lineX, ty, lineStyle)
+
+		// The arrow heads, pointing towards the extension lines
+		if ty-fy > arrowSize*2 {
+			ctx.Canvas.Polygon(
+				[]int{lineX, lineX - arrowSize/2, lineX + arrowSize/2},
+				[]int{fy, fy + arrowSize, fy + arrowSize}, headStyle)
+			ctx.Canvas.Polygon(
+				[]int{lineX, lineX - arrowSize/2, lineX + arrowSize/2},
+				[]int{ty, ty - arrowSize, ty - arrowSize}, headStyle)
+		}
+
+		textX := lineX - arrowSize/2 - dl.style.TextGap
+		dl.textBox.Render(ctx, textX, fy+(ty-fy)/2, EastGravity)
+	}
+}
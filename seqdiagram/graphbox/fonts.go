@@ -4,26 +4,55 @@ package graphbox
 
 import (
 	"bytes"
-	"image"
-	"image/color"
-	"math"
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"unicode"
 
-	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
 
 	"golang.org/x/image/font"
-	"golang.org/x/image/math/fixed"
 )
 
 type Font interface {
 	// Returns the appropriate name of this font in the SVG
 	SvgName() string
 
-	// Measures the size of the particular line of text
+	// Measures the size of the particular line of text.  The height is the line height
+	// of the font.
 	Measure(txt string, size float64) (int, int)
+
+	// Returns the distance from the top of a line of text to its baseline
+	Ascent(txt string, size float64) int
+}
+
+// A font which can be embedded within an SVG as a font face
+type EmbeddableFont interface {
+	Font
+
+	// Returns the name of the font family declared by the font face
+	FamilyName() string
+
+	// Returns the name of the font file, used when the fonts are referenced by URL
+	FileName() string
+
+	// Returns the CSS font weight and style of the font, such as "bold" and "italic"
+	Weight() string
+	Style() string
+
+	// Returns the TrueType data of the font with only the glyphs needed to draw the runes,
+	// or an error if the font cannot be subset
+	Subset(runes []rune) ([]byte, error)
+}
+
+// A font which draws the runes it has no glyphs for with fallback fonts
+type FallbackFont interface {
+	Font
+
+	// Returns the font which draws a rune
+	FontFor(r rune) Font
 }
 
 // Given a font, font size, points and gravity, returns a rectangle which will contain
@@ -33,9 +62,7 @@ type Font interface {
 func MeasureFontRect(font Font, size int, text string, x, y int, gravity Gravity) (Rect, Point) {
 	w, h := font.Measure(text, float64(size))
 	ox, oy := gravity(w, h)
-	tp := Point{x - ox, y - oy + h}
-	// HACK: May now work in all cases.  Adjust for the hanging measurements
-	tp.Y -= size*1/4 - 1
+	tp := Point{x - ox, y - oy + font.Ascent(text, float64(size))}
 	return Rect{x - ox, y - oy, w, h}, tp
 }
 
@@ -43,9 +70,30 @@ func MeasureFontRect(font Font, size int, text string, x, y int, gravity Gravity
 type TTFFont struct {
 	font     *truetype.Font
 	fontName string
+	data     []byte
+
+	// The file name, weight and style of the font
+	fileName string
+	weight   string
+	style    string
+
+	// The fonts used to draw runes, starting with the font without any fallbacks.  Empty
+	// if the font has no fallbacks.
+	chain []*TTFFont
+
+	// The faces of the font used for measuring text, by font size
+	faces *faceCache
 }
 
-// Returns a new TTFFont struct
+// The faces of a font by font size.  Faces are not safe for concurrent use, so the
+// faces are only used while the cache is locked.
+type faceCache struct {
+	sync.Mutex
+	faces map[float64]font.Face
+}
+
+// Returns a new TTFFont struct.  The font is named after the family declared within the
+// font, and is bold or italic if the subfamily of the font is.
 func NewTTFFont(path string) (*TTFFont, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -59,7 +107,26 @@ func NewTTFFont(path string) (*TTFFont, error) {
 		return nil, err
 	}
 
-	return NewTTFFontFromByteSlice(buffer.Bytes(), strings.TrimSuffix(filepath.Base(path), ".ttf"))
+	fileName := filepath.Base(path)
+	ttf, err := NewTTFFontFromByteSlice(buffer.Bytes(), strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+	if err != nil {
+		return nil, err
+	}
+
+	ttf.fileName = fileName
+	if family := ttf.font.Name(truetype.NameIDFontFamily); family != "" {
+		ttf.fontName = family
+	}
+
+	subfamily := strings.ToLower(ttf.font.Name(truetype.NameIDFontSubfamily))
+	if strings.Contains(subfamily, "bold") {
+		ttf.weight = "bold"
+	}
+	if strings.Contains(subfamily, "italic") || strings.Contains(subfamily, "oblique") {
+		ttf.style = "italic"
+	}
+
+	return ttf, nil
 }
 
 // Returns a new TTFFont from a reader and name
@@ -69,70 +136,219 @@ func NewTTFFontFromBuffer(fontBuffer *bytes.Buffer, name string) (*TTFFont, erro
 }
 */
 
-// Loads a TTF font from a byte slice
+// Loads a TTF font from a byte slice.  Only fonts with TrueType outlines are supported.
 func NewTTFFontFromByteSlice(bytes []byte, fontName string) (*TTFFont, error) {
-	ttfFont, err := freetype.ParseFont(bytes)
+	if len(bytes) >= 4 {
+		switch string(bytes[:4]) {
+		case "OTTO":
+			return nil, errors.New("OpenType fonts with CFF outlines are not supported, only fonts with TrueType outlines")
+		case "ttcf":
+			return nil, errors.New("Font collections are not supported, only single TrueType fonts")
+		}
+	}
+
+	ttfFont, err := truetype.Parse(bytes)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TTFFont{ttfFont, fontName}, nil
+	return &TTFFont{
+		font:     ttfFont,
+		fontName: fontName,
+		data:     bytes,
+		fileName: fontName + ".ttf",
+		weight:   "normal",
+		style:    "normal",
+		faces:    &faceCache{faces: make(map[float64]font.Face)},
+	}, nil
 }
 
-// Measures the size of a font
-// TODO: For long bits of text, the measurement can be slightly off
-func (ttf *TTFFont) Measure(txt string, size float64) (int, int) {
-	img := nopDrawImage(0)
+// Returns a copy of the font which draws the runes it has no glyphs for with the first of
+// the fallback fonts which has a glyph for the rune
+func (ttf *TTFFont) WithFallbacks(fallbacks ...*TTFFont) *TTFFont {
+	fallbackFont := *ttf
+	fallbackFont.chain = append([]*TTFFont{ttf.primary()}, ttf.fallbacks()...)
+	for _, fallback := range fallbacks {
+		fallbackFont.chain = append(fallbackFont.chain, fallback.primary())
+	}
+	return &fallbackFont
+}
+
+// Returns the font without any fallbacks
+func (ttf *TTFFont) primary() *TTFFont {
+	if len(ttf.chain) > 0 {
+		return ttf.chain[0]
+	}
+	return ttf
+}
+
+// Returns the fallback fonts
+func (ttf *TTFFont) fallbacks() []*TTFFont {
+	if len(ttf.chain) > 0 {
+		return ttf.chain[1:]
+	}
+	return nil
+}
 
-	ctx := freetype.NewContext()
-	ctx.SetDPI(72)
-	ctx.SetClip(img.Bounds())
-	ctx.SetSrc(img)
-	ctx.SetDst(img)
-	ctx.SetFont(ttf.font)
-	ctx.SetHinting(font.HintingFull)
-	ctx.SetFontSize(size)
+// Returns the font which draws a rune.  This is the first font of the fallback chain with a
+// glyph for the rune, or the font itself if none of the fonts have a glyph.
+func (ttf *TTFFont) FontFor(r rune) Font {
+	return ttf.ttfFontFor(r)
+}
 
-	np, _ := ctx.DrawString(txt, freetype.Pt(0, 0))
+func (ttf *TTFFont) ttfFontFor(r rune) *TTFFont {
+	for _, chainFont := range ttf.chain {
+		if chainFont.font.Index(r) != 0 {
+			return chainFont
+		}
+	}
+	return ttf.primary()
+}
 
-	mx, my := ttf.roundFix32(np.X), int(size)+ttf.roundFix32(np.Y)
+// Measures the size of a font.  If the font has fallbacks, the text is split into runs of
+// runes drawn by the same font, and each run is measured with its font.
+func (ttf *TTFFont) Measure(txt string, size float64) (int, int) {
+	if (len(ttf.chain) == 0) || (txt == "") {
+		return ttf.measureRun(txt, size)
+	}
+
+	w, h := 0, 0
+	for _, run := range ttf.runs(txt) {
+		rw, rh := run.font.measureRun(run.text, size)
+		w, h = w+rw, maxInt(h, rh)
+	}
+	return w, h
+}
 
-	return mx, my
+// A run of text drawn by the same font
+type fontRun struct {
+	font *TTFFont
+	text string
 }
 
-// Round a 26.6 fixed number to the nearest integer.
-func (ttf *TTFFont) roundFix32(x fixed.Int26_6) int {
-	full := int(x >> 6)
-	rem := int(x & 0xCF)
+// Splits text into runs of runes drawn by the same font of the fallback chain
+func (ttf *TTFFont) runs(txt string) []fontRun {
+	runs := make([]fontRun, 0, 1)
+	runStart := 0
+	var runFont *TTFFont
 
-	if rem > 0 {
-		return full + 1
-	} else {
-		return full
+	for i, r := range txt {
+		if runeFont := ttf.ttfFontFor(r); runeFont != runFont {
+			if runFont != nil {
+				runs = append(runs, fontRun{runFont, txt[runStart:i]})
+			}
+			runStart, runFont = i, runeFont
+		}
 	}
+	if runFont != nil {
+		runs = append(runs, fontRun{runFont, txt[runStart:]})
+	}
+	return runs
 }
 
-// Return the SVG Name
-func (ttf *TTFFont) SvgName() string {
-	// !!HACK!!  Need to properly determine how specific font families are defined in SVG
-	return ttf.fontName + ",sans-serif"
+// Returns the distance from the top of a line of text to its baseline.  If the font has
+// fallbacks, this is the largest ascent of the fonts drawing the text.
+func (ttf *TTFFont) Ascent(txt string, size float64) int {
+	if (len(ttf.chain) == 0) || (txt == "") {
+		return ttf.primary().ascentOf(size)
+	}
+
+	ascent := 0
+	for _, run := range ttf.runs(txt) {
+		ascent = maxInt(ascent, run.font.ascentOf(size))
+	}
+	return ascent
 }
 
-// A no-op drawable image used for measuring the font
-type nopDrawImage int
+// Measures the size of text drawn entirely with the font.  The width is the sum of the
+// glyph advances adjusted by kerning, and the height is the line height of the font.
+func (ttf *TTFFont) measureRun(txt string, size float64) (int, int) {
+	var w, h int
+	ttf.withFace(size, func(face font.Face) {
+		w = font.MeasureString(face, txt).Ceil()
+		h = face.Metrics().Height.Ceil()
+	})
+	return w, h
+}
 
-func (ndi nopDrawImage) ColorModel() color.Model {
-	return color.GrayModel
+// Returns the ascent of the font
+func (ttf *TTFFont) ascentOf(size float64) int {
+	var ascent int
+	ttf.withFace(size, func(face font.Face) {
+		ascent = face.Metrics().Ascent.Ceil()
+	})
+	return ascent
 }
-func (ndi nopDrawImage) Bounds() image.Rectangle {
-	return image.Rectangle{
-		Min: image.Point{int(math.MinInt32), int(math.MinInt32)},
-		Max: image.Point{int(math.MaxInt32), int(math.MaxInt32)},
+
+// Calls a function with the face of the font at a font size.  The glyphs are not hinted
+// so the measurements match the outlines drawn at any scale.
+func (ttf *TTFFont) withFace(size float64, fn func(face font.Face)) {
+	ttf.faces.Lock()
+	defer ttf.faces.Unlock()
+
+	face, hasFace := ttf.faces.faces[size]
+	if !hasFace {
+		face = truetype.NewFace(ttf.font, &truetype.Options{
+			Size:    size,
+			DPI:     72,
+			Hinting: font.HintingNone,
+		})
+		ttf.faces.faces[size] = face
 	}
+	fn(face)
 }
-func (ndi nopDrawImage) At(x, y int) color.Color {
-	return color.Black
+
+// Returns the name of the font family
+func (ttf *TTFFont) FamilyName() string {
+	return ttf.fontName
+}
+
+// Returns the name of the font file
+func (ttf *TTFFont) FileName() string {
+	return ttf.fileName
+}
+
+// Returns the CSS font weight
+func (ttf *TTFFont) Weight() string {
+	return ttf.weight
+}
+
+// Returns the CSS font style
+func (ttf *TTFFont) Style() string {
+	return ttf.style
+}
+
+// Returns the TrueType data of the font with only the glyphs needed to draw the runes
+func (ttf *TTFFont) Subset(runes []rune) ([]byte, error) {
+	return subsetTTF(ttf.data, runes, func(r rune) int { return int(ttf.font.Index(r)) })
 }
-func (ndi nopDrawImage) Set(x, y int, c color.Color) {
-	// Do nothing
+
+// Return the SVG Name.  This lists the family of the font followed by the families of
+// the fallback fonts.
+func (ttf *TTFFont) SvgName() string {
+	return ttf.familyList((*TTFFont).FamilyName)
+}
+
+// Returns the CSS font family list of the font and its fallback fonts, with the family of
+// each font given by a function
+func (ttf *TTFFont) familyList(familyOf func(font *TTFFont) string) string {
+	families := []string{cssFamilyName(familyOf(ttf.primary()))}
+	for _, fallback := range ttf.fallbacks() {
+		family := cssFamilyName(familyOf(fallback))
+		if !containsString(families, family) {
+			families = append(families, family)
+		}
+	}
+	return strings.Join(append(families, "sans-serif"), ",")
+}
+
+// Returns the name of a font family for use in CSS.  Family names with characters other
+// than letters, digits and hyphens are quoted.
+func cssFamilyName(family string) string {
+	if strings.IndexFunc(family, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-')
+	}) >= 0 {
+		return "'" + family + "'"
+	}
+	return family
 }
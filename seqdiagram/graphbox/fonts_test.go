@@ -0,0 +1,127 @@
+package graphbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func TestNewTTFFontFromByteSlice(t *testing.T) {
+	assert := assert.Assert(t)
+
+	font, err := NewTTFFontFromByteSlice(goregular.TTF, "Go")
+	assert.Equal(err, nil)
+	assert.Equal(font.FamilyName(), "Go")
+}
+
+func TestNewTTFFontUnsupportedFormats(t *testing.T) {
+	assert := assert.Assert(t)
+
+	_, err := NewTTFFontFromByteSlice([]byte("OTTO\x00\x0a\x00\x80"), "Font")
+	assert.Equal(err != nil && strings.Contains(err.Error(), "CFF outlines"), true)
+
+	_, err = NewTTFFontFromByteSlice([]byte("ttcf\x00\x01\x00\x00"), "Font")
+	assert.Equal(err != nil && strings.Contains(err.Error(), "collections"), true)
+}
+
+func TestTTFFontMeasure(t *testing.T) {
+	assert := assert.Assert(t)
+
+	ttf, err := NewTTFFontFromByteSlice(goregular.TTF, "Go")
+	assert.Equal(err, nil)
+
+	w, h := ttf.Measure("Hello, world", 16)
+	assert.Equal(w, 87)
+	assert.Equal(h, 16)
+	assert.Equal(ttf.Ascent("Hello, world", 16), 16)
+
+	w, _ = ttf.Measure("", 16)
+	assert.Equal(w, 0)
+}
+
+func TestTTFFontFacesCachedBySize(t *testing.T) {
+	assert := assert.Assert(t)
+
+	ttf, err := NewTTFFontFromByteSlice(goregular.TTF, "Go")
+	assert.Equal(err, nil)
+
+	faceOf := func(ttf *TTFFont, size float64) (sizeFace font.Face) {
+		ttf.withFace(size, func(face font.Face) { sizeFace = face })
+		return sizeFace
+	}
+
+	assert.Equal(faceOf(ttf, 16) == faceOf(ttf, 16), true)
+	assert.Equal(faceOf(ttf, 16) == faceOf(ttf, 12), false)
+	assert.Equal(len(ttf.faces.faces), 2)
+
+	// Copies of the font with fallbacks share the faces of the font
+	assert.Equal(faceOf(ttf.WithFallbacks(), 16) == faceOf(ttf, 16), true)
+}
+
+// Returns a font named "Sub" with only the glyphs of the runes of Go Regular, which falls
+// back to Go Regular
+func subsetWithFallback(t *testing.T, runes string) (*TTFFont, *TTFFont, *TTFFont) {
+	goFont, err := NewTTFFontFromByteSlice(goregular.TTF, "Go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subsetData, err := goFont.Subset([]rune(runes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subFont, err := NewTTFFontFromByteSlice(subsetData, "Sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return subFont.WithFallbacks(goFont), subFont, goFont
+}
+
+func TestTTFFontRunsWithFallbacks(t *testing.T) {
+	assert := assert.Assert(t)
+
+	ttf, subFont, goFont := subsetWithFallback(t, "Helo")
+
+	assert.Equal(ttf.ttfFontFor('H'), subFont)
+	assert.Equal(ttf.ttfFontFor(','), goFont)
+	assert.Equal(ttf.ttfFontFor('\u4e16'), subFont)
+
+	assert.Equal(ttf.runs("Hello, world\u4e16"), []fontRun{
+		{subFont, "Hello"},
+		{goFont, ", w"},
+		{subFont, "o"},
+		{goFont, "r"},
+		{subFont, "l"},
+		{goFont, "d"},
+		{subFont, "\u4e16"},
+	})
+	assert.Equal(ttf.runs("Hello"), []fontRun{{subFont, "Hello"}})
+}
+
+func TestTTFFontMeasureWithFallbacks(t *testing.T) {
+	assert := assert.Assert(t)
+
+	ttf, _, goFont := subsetWithFallback(t, "Helo")
+
+	helloWidth, _ := goFont.Measure("Hello", 16)
+	worldWidth, _ := goFont.Measure(", w", 16)
+	w, h := ttf.Measure("Hello, w", 16)
+	assert.Equal(w, helloWidth+worldWidth)
+	assert.Equal(h, 16)
+}
+
+func TestTTFFontFamilyListWithFallbacks(t *testing.T) {
+	assert := assert.Assert(t)
+
+	ttf, subFont, goFont := subsetWithFallback(t, "Helo")
+
+	assert.Equal(subFont.SvgName(), "Sub,sans-serif")
+	assert.Equal(ttf.SvgName(), "Sub,Go,sans-serif")
+	assert.Equal(ttf.WithFallbacks(goFont).SvgName(), "Sub,Go,sans-serif")
+	assert.Equal(ttf.familyList(func(font *TTFFont) string { return font.FamilyName() + " Subset" }),
+		"'Sub Subset','Go Subset',sans-serif")
+}
@@ -0,0 +1,498 @@
+// Subsetting of TrueType fonts
+
+package graphbox
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// The tables which are copied unchanged from the original font into the subset, if
+// they are present.  Tables describing layout features refer to the original glyph
+// indices and are dropped, except for the kern table which is renumbered.
+var subsetCopiedTables = []string{"OS/2", "cvt ", "fpgm", "gasp", "prep"}
+
+// The highest name ID kept in the name table of the subset.  The names up to the
+// PostScript name identify the font, while the later names, such as the licence, can
+// be many times larger than the glyphs of a subset.
+const subsetMaxNameID = 6
+
+// Flags of the components of composite glyphs
+const (
+	compositeArgsAreWords   = 0x0001
+	compositeHaveScale      = 0x0008
+	compositeMoreComponents = 0x0020
+	compositeHaveXYScale    = 0x0040
+	compositeHaveTwoByTwo   = 0x0080
+)
+
+// A table of a TrueType font
+type fontTable struct {
+	tag  string
+	data []byte
+}
+
+// Returns a TrueType font with only the glyphs of the runes.  The glyphs are renumbered,
+// with the cmap, glyf, loca and hmtx tables rebuilt to match, so that the subset is only as
+// large as the glyphs it contains.  The index function returns the glyph index of a rune.
+func subsetTTF(data []byte, runes []rune, index func(r rune) int) ([]byte, error) {
+	tables, err := readFontTables(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "loca", "glyf"} {
+		if _, hasTable := tables[tag]; !hasTable {
+			return nil, errors.New("Font cannot be subset: no " + tag + " table")
+		}
+	}
+
+	head, hhea, maxp := tables["head"], tables["hhea"], tables["maxp"]
+	if len(head) < 54 || len(hhea) < 36 || len(maxp) < 6 {
+		return nil, errors.New("Font cannot be subset: truncated tables")
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	glyphs, err := readGlyphs(tables["glyf"], tables["loca"], numGlyphs, binary.BigEndian.Uint16(head[50:]) != 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// Glyph 0 is the missing glyph, and is always kept.  The glyphs of the runes are
+	// kept along with the components of any composite glyphs.
+	keep := map[int]bool{0: true}
+	var keepGlyph func(gid int)
+	keepGlyph = func(gid int) {
+		if gid < 0 || gid >= numGlyphs || keep[gid] {
+			return
+		}
+		keep[gid] = true
+		for _, component := range compositeComponents(glyphs[gid]) {
+			keepGlyph(int(binary.BigEndian.Uint16(glyphs[gid][component:])))
+		}
+	}
+
+	runeGlyphs := make(map[rune]int)
+	for _, r := range runes {
+		if gid := index(r); gid > 0 {
+			runeGlyphs[r] = gid
+			keepGlyph(gid)
+		}
+	}
+
+	oldGids := make([]int, 0, len(keep))
+	for gid := range keep {
+		oldGids = append(oldGids, gid)
+	}
+	sort.Ints(oldGids)
+
+	newGids := make(map[int]int, len(oldGids))
+	for newGid, oldGid := range oldGids {
+		newGids[oldGid] = newGid
+	}
+
+	// Build the glyf and long loca tables, with the components renumbered
+	glyf := make([]byte, 0)
+	loca := make([]byte, 0, (len(oldGids)+1)*4)
+	for _, oldGid := range oldGids {
+		loca = appendUint32(loca, uint32(len(glyf)))
+
+		glyph := append([]byte(nil), glyphs[oldGid]...)
+		for _, component := range compositeComponents(glyph) {
+			oldComponent := int(binary.BigEndian.Uint16(glyph[component:]))
+			binary.BigEndian.PutUint16(glyph[component:], uint16(newGids[oldComponent]))
+		}
+
+		glyf = append(glyf, glyph...)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	loca = appendUint32(loca, uint32(len(glyf)))
+
+	// Every glyph of the subset is given a long horizontal metric
+	hmtx := tables["hmtx"]
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numHMetrics == 0 || len(hmtx) < numHMetrics*4+(numGlyphs-numHMetrics)*2 {
+		return nil, errors.New("Font cannot be subset: truncated hmtx table")
+	}
+
+	newHmtx := make([]byte, 0, len(oldGids)*4)
+	for _, oldGid := range oldGids {
+		if oldGid < numHMetrics {
+			newHmtx = append(newHmtx, hmtx[oldGid*4:oldGid*4+4]...)
+		} else {
+			lsbOffset := numHMetrics*4 + (oldGid-numHMetrics)*2
+			newHmtx = append(newHmtx, hmtx[(numHMetrics-1)*4:(numHMetrics-1)*4+2]...)
+			newHmtx = append(newHmtx, hmtx[lsbOffset:lsbOffset+2]...)
+		}
+	}
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0)
+	binary.BigEndian.PutUint16(newHead[50:], 1)
+
+	newHhea := append([]byte(nil), hhea...)
+	binary.BigEndian.PutUint16(newHhea[34:], uint16(len(oldGids)))
+
+	newMaxp := append([]byte(nil), maxp...)
+	binary.BigEndian.PutUint16(newMaxp[4:], uint16(len(oldGids)))
+
+	newRuneGlyphs := make(map[rune]int, len(runeGlyphs))
+	for r, gid := range runeGlyphs {
+		newRuneGlyphs[r] = newGids[gid]
+	}
+
+	subset := []fontTable{
+		{"cmap", buildCmap(newRuneGlyphs)},
+		{"glyf", glyf},
+		{"head", newHead},
+		{"hhea", newHhea},
+		{"hmtx", newHmtx},
+		{"loca", loca},
+		{"maxp", newMaxp},
+	}
+
+	// The glyph names are dropped from the post table
+	if post, hasPost := tables["post"]; hasPost && len(post) >= 32 {
+		newPost := append([]byte(nil), post[:32]...)
+		binary.BigEndian.PutUint32(newPost, 0x00030000)
+		subset = append(subset, fontTable{"post", newPost})
+	}
+
+	if name, hasName := tables["name"]; hasName {
+		subset = append(subset, fontTable{"name", subsetNames(name)})
+	}
+
+	// The kerning pairs are kept so that text is drawn with the widths it is measured with
+	if kern, hasKern := tables["kern"]; hasKern {
+		if newKern, isSubset := subsetKern(kern, newGids); isSubset {
+			subset = append(subset, fontTable{"kern", newKern})
+		}
+	}
+
+	for _, tag := range subsetCopiedTables {
+		if table, hasTable := tables[tag]; hasTable {
+			subset = append(subset, fontTable{tag, table})
+		}
+	}
+
+	return writeFontTables(subset), nil
+}
+
+// Reads the tables of a TrueType font, keyed by tag
+func readFontTables(data []byte) (map[string][]byte, error) {
+	if len(data) < 12 {
+		return nil, errors.New("Font cannot be subset: not a TrueType font")
+	} else if binary.BigEndian.Uint32(data) != 0x00010000 && string(data[:4]) != "true" {
+		return nil, errors.New("Font cannot be subset: not a TrueType font")
+	}
+
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	if len(data) < 12+numTables*16 {
+		return nil, errors.New("Font cannot be subset: truncated table directory")
+	}
+
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < numTables; i++ {
+		record := data[12+i*16:]
+		offset, length := binary.BigEndian.Uint32(record[8:]), binary.BigEndian.Uint32(record[12:])
+		if uint64(offset)+uint64(length) > uint64(len(data)) {
+			return nil, errors.New("Font cannot be subset: truncated " + string(record[:4]) + " table")
+		}
+		tables[string(record[:4])] = data[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// Splits the glyf table into the data of each glyph
+func readGlyphs(glyf []byte, loca []byte, numGlyphs int, longLoca bool) ([][]byte, error) {
+	offsetAt := func(i int) int {
+		if longLoca {
+			return int(binary.BigEndian.Uint32(loca[i*4:]))
+		}
+		return int(binary.BigEndian.Uint16(loca[i*2:])) * 2
+	}
+
+	if (longLoca && len(loca) < (numGlyphs+1)*4) || (!longLoca && len(loca) < (numGlyphs+1)*2) {
+		return nil, errors.New("Font cannot be subset: truncated loca table")
+	}
+
+	glyphs := make([][]byte, numGlyphs)
+	for i := range glyphs {
+		from, to := offsetAt(i), offsetAt(i+1)
+		if from > to || to > len(glyf) {
+			return nil, errors.New("Font cannot be subset: invalid loca table")
+		}
+		glyphs[i] = glyf[from:to]
+	}
+	return glyphs, nil
+}
+
+// Returns the offsets of the glyph indices of the components of a composite glyph.
+// Simple glyphs have no components.
+func compositeComponents(glyph []byte) []int {
+	if len(glyph) < 10 || int16(binary.BigEndian.Uint16(glyph)) >= 0 {
+		return nil
+	}
+
+	components := make([]int, 0)
+	for offset := 10; offset+4 <= len(glyph); {
+		flags := binary.BigEndian.Uint16(glyph[offset:])
+		components = append(components, offset+2)
+
+		offset += 4
+		if flags&compositeArgsAreWords != 0 {
+			offset += 4
+		} else {
+			offset += 2
+		}
+
+		if flags&compositeHaveScale != 0 {
+			offset += 2
+		} else if flags&compositeHaveXYScale != 0 {
+			offset += 4
+		} else if flags&compositeHaveTwoByTwo != 0 {
+			offset += 8
+		}
+
+		if flags&compositeMoreComponents == 0 {
+			break
+		}
+	}
+	return components
+}
+
+// Returns the name table with only the names which identify the font.  If the table
+// cannot be read, it is returned unchanged.
+func subsetNames(name []byte) []byte {
+	if len(name) < 6 {
+		return name
+	}
+
+	count, stringOffset := int(binary.BigEndian.Uint16(name[2:])), int(binary.BigEndian.Uint16(name[4:]))
+	if len(name) < 6+count*12 || stringOffset > len(name) {
+		return name
+	}
+
+	records := make([][]byte, 0, count)
+	nameData := make([]byte, 0)
+	for i := 0; i < count; i++ {
+		record := append([]byte(nil), name[6+i*12:6+i*12+12]...)
+		nameID := binary.BigEndian.Uint16(record[6:])
+		length, offset := int(binary.BigEndian.Uint16(record[8:])), int(binary.BigEndian.Uint16(record[10:]))
+		if nameID > subsetMaxNameID || stringOffset+offset+length > len(name) {
+			continue
+		}
+
+		binary.BigEndian.PutUint16(record[10:], uint16(len(nameData)))
+		nameData = append(nameData, name[stringOffset+offset:stringOffset+offset+length]...)
+		records = append(records, record)
+	}
+
+	newName := appendUint16(nil, 0, uint16(len(records)), uint16(6+len(records)*12))
+	for _, record := range records {
+		newName = append(newName, record...)
+	}
+	return append(newName, nameData...)
+}
+
+// Returns the kern table with only the pairs of glyphs kept in the subset, renumbered
+// with the new glyph indices.  Only the format 0 subtables of version 0 tables are
+// kept.  Returns false if the table cannot be read or no pairs are kept.
+func subsetKern(kern []byte, newGids map[int]int) ([]byte, bool) {
+	if len(kern) < 4 || binary.BigEndian.Uint16(kern) != 0 {
+		return nil, false
+	}
+
+	nTables := int(binary.BigEndian.Uint16(kern[2:]))
+	subtables := make([][]byte, 0, nTables)
+	offset := 4
+	for i := 0; i < nTables; i++ {
+		if offset+14 > len(kern) {
+			return nil, false
+		}
+		length, coverage := int(binary.BigEndian.Uint16(kern[offset+2:])), binary.BigEndian.Uint16(kern[offset+4:])
+		nPairs := int(binary.BigEndian.Uint16(kern[offset+6:]))
+		if offset+14+nPairs*6 > len(kern) {
+			return nil, false
+		}
+
+		if coverage>>8 == 0 {
+			pairs := make([][]byte, 0)
+			for p := 0; p < nPairs; p++ {
+				pair := append([]byte(nil), kern[offset+14+p*6:offset+20+p*6]...)
+				left, hasLeft := newGids[int(binary.BigEndian.Uint16(pair))]
+				right, hasRight := newGids[int(binary.BigEndian.Uint16(pair[2:]))]
+				if hasLeft && hasRight {
+					binary.BigEndian.PutUint16(pair, uint16(left))
+					binary.BigEndian.PutUint16(pair[2:], uint16(right))
+					pairs = append(pairs, pair)
+				}
+			}
+
+			// The pairs are searched by the left and right glyph indices
+			sort.Slice(pairs, func(i, j int) bool {
+				return binary.BigEndian.Uint32(pairs[i]) < binary.BigEndian.Uint32(pairs[j])
+			})
+
+			if len(pairs) > 0 {
+				searchRange, entrySelector := 1, 0
+				for searchRange*2 <= len(pairs) {
+					searchRange, entrySelector = searchRange*2, entrySelector+1
+				}
+
+				subtable := appendUint16(nil, 0, uint16(14+len(pairs)*6), coverage, uint16(len(pairs)),
+					uint16(searchRange*6), uint16(entrySelector), uint16((len(pairs)-searchRange)*6))
+				for _, pair := range pairs {
+					subtable = append(subtable, pair...)
+				}
+				subtables = append(subtables, subtable)
+			}
+		}
+
+		// The length of format 0 subtables with many pairs can overflow, so it is
+		// calculated from the number of pairs
+		if coverage>>8 == 0 {
+			length = 14 + nPairs*6
+		} else if length < 6 {
+			return nil, false
+		}
+		offset += length
+	}
+
+	if len(subtables) == 0 {
+		return nil, false
+	}
+
+	newKern := appendUint16(nil, 0, uint16(len(subtables)))
+	for _, subtable := range subtables {
+		newKern = append(newKern, subtable...)
+	}
+	return newKern, true
+}
+
+// Builds a cmap table mapping the runes to glyph indices.  The table has a format 4
+// subtable for the runes of the basic multilingual plane and a format 12 subtable for
+// all runes.
+func buildCmap(runeGlyphs map[rune]int) []byte {
+	runes := make([]rune, 0, len(runeGlyphs))
+	for r := range runeGlyphs {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	// Format 4, with a segment for each rune and the terminating segment
+	bmpRunes := make([]rune, 0, len(runes))
+	for _, r := range runes {
+		if r < 0xFFFF {
+			bmpRunes = append(bmpRunes, r)
+		}
+	}
+
+	segCount := len(bmpRunes) + 1
+	searchRange := 2
+	entrySelector := 0
+	for searchRange*2 <= segCount*2 {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	format4 := make([]byte, 0, 16+segCount*8)
+	format4 = appendUint16(format4, 4, uint16(16+segCount*8), 0, uint16(segCount*2),
+		uint16(searchRange), uint16(entrySelector), uint16(segCount*2-searchRange))
+	for _, r := range bmpRunes {
+		format4 = appendUint16(format4, uint16(r))
+	}
+	format4 = appendUint16(format4, 0xFFFF, 0)
+	for _, r := range bmpRunes {
+		format4 = appendUint16(format4, uint16(r))
+	}
+	format4 = appendUint16(format4, 0xFFFF)
+	for _, r := range bmpRunes {
+		format4 = appendUint16(format4, uint16(runeGlyphs[r]-int(r)))
+	}
+	format4 = appendUint16(format4, 1)
+	for i := 0; i < segCount; i++ {
+		format4 = appendUint16(format4, 0)
+	}
+
+	// Format 12, with a group for each rune
+	format12 := make([]byte, 0, 16+len(runes)*12)
+	format12 = appendUint16(format12, 12, 0)
+	format12 = appendUint32(format12, uint32(16+len(runes)*12), 0, uint32(len(runes)))
+	for _, r := range runes {
+		format12 = appendUint32(format12, uint32(r), uint32(r), uint32(runeGlyphs[r]))
+	}
+
+	cmap := appendUint16(nil, 0, 2)
+	cmap = appendUint16(cmap, 3, 1)
+	cmap = appendUint32(cmap, 20)
+	cmap = appendUint16(cmap, 3, 10)
+	cmap = appendUint32(cmap, uint32(20+len(format4)))
+	cmap = append(cmap, format4...)
+	return append(cmap, format12...)
+}
+
+// Writes the tables as a TrueType font
+func writeFontTables(tables []fontTable) []byte {
+	sort.Slice(tables, func(i, j int) bool { return tables[i].tag < tables[j].tag })
+
+	searchRange, entrySelector := 1, 0
+	for searchRange*2 <= len(tables) {
+		searchRange *= 2
+		entrySelector++
+	}
+
+	font := appendUint32(nil, 0x00010000)
+	font = appendUint16(font, uint16(len(tables)), uint16(searchRange*16), uint16(entrySelector),
+		uint16((len(tables)-searchRange)*16))
+
+	offset := len(font) + len(tables)*16
+	headOffset := 0
+	for _, table := range tables {
+		if table.tag == "head" {
+			headOffset = offset
+		}
+
+		font = append(font, table.tag...)
+		font = appendUint32(font, fontChecksum(table.data), uint32(offset), uint32(len(table.data)))
+		offset += (len(table.data) + 3) &^ 3
+	}
+
+	for _, table := range tables {
+		font = append(font, table.data...)
+		for len(font)%4 != 0 {
+			font = append(font, 0)
+		}
+	}
+
+	binary.BigEndian.PutUint32(font[headOffset+8:], 0xB1B0AFBA-fontChecksum(font))
+	return font
+}
+
+// Returns the checksum of font data, which is the sum of the data as 32-bit words
+func fontChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
+
+func appendUint16(b []byte, values ...uint16) []byte {
+	for _, v := range values {
+		b = append(b, byte(v>>8), byte(v))
+	}
+	return b
+}
+
+func appendUint32(b []byte, values ...uint32) []byte {
+	for _, v := range values {
+		b = append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+	return b
+}
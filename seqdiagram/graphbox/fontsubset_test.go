@@ -0,0 +1,47 @@
+package graphbox
+
+import (
+	"testing"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/seanpont/assert"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestSubsetTTF(t *testing.T) {
+	assert := assert.Assert(t)
+
+	original, err := truetype.Parse(goregular.TTF)
+	assert.Equal(err, nil)
+
+	runes := []rune("AVWaefoöé,. ")
+	data, err := subsetTTF(goregular.TTF, runes, func(r rune) int { return int(original.Index(r)) })
+	assert.Equal(err, nil)
+	assert.Equal(len(data) < len(goregular.TTF), true)
+
+	subset, err := truetype.Parse(data)
+	assert.Equal(err, nil)
+
+	scale := fixed.I(1000)
+	for _, r := range runes {
+		if subset.Index(r) == 0 {
+			t.Errorf("Rune %q is missing from the subset", r)
+			continue
+		}
+		assert.Equal(subset.HMetric(scale, subset.Index(r)), original.HMetric(scale, original.Index(r)))
+		for _, next := range runes {
+			assert.Equal(subset.Kern(scale, subset.Index(r), subset.Index(next)),
+				original.Kern(scale, original.Index(r), original.Index(next)))
+		}
+	}
+
+	assert.Equal(subset.Index('z'), truetype.Index(0))
+}
+
+func TestSubsetTTFInvalidFont(t *testing.T) {
+	assert := assert.Assert(t)
+
+	_, err := subsetTTF([]byte("not a font"), []rune("a"), func(r rune) int { return 1 })
+	assert.Equal(err != nil, true)
+}
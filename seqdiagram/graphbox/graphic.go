@@ -4,12 +4,23 @@
 package graphbox
 
 import (
+	"bytes"
+	"encoding/base64"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/ajstarks/svgo"
 )
 
+// The font URL which omits the font faces from the SVG
+const NoFontURL = "none"
+
+// Escapes a font URL for the style sheet
+var fontURLEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "'", "%27")
+
 // // Options for the SVG images
 // type SvgOptions struct {
 //     // If true, the viewport attribute for the SVG diagram will be set and the
@@ -33,6 +44,52 @@ type Graphic struct {
 	// If true, generate a 'viewport' attribute with the image size and
 	// use percentages for the original image size
 	Viewport bool
+
+	// The scale factor of the output image.  Values of 0 or 1 will produce
+	// an image at its natural size.
+	Scale float64
+
+	// The background colour.  If blank, the background is transparent.
+	Background string
+
+	// If true, the styles of the elements are written to a style sheet as classes
+	// instead of being set on each element, so that they can be overridden by other
+	// style sheets.
+	StyleClasses bool
+
+	// The URL of the fonts declared by the font faces of the SVG.  If blank, the fonts are
+	// embedded within the SVG with only the glyphs which are drawn.  If the URL ends with a
+	// slash, the font is named by appending the family name and ".ttf" to the URL.  If the
+	// URL is NoFontURL, the font faces are left out and the viewer chooses the fonts.
+	FontURL string
+
+	// If true, the style sheet with the font faces and style classes is left out of the
+	// SVG.  It is written separately with WriteStyleSheet, so that several drawings of the
+	// graphic within the same document share the one style sheet.
+	OmitStyleSheet bool
+
+	// The classes of the styles used while drawing, keyed by style
+	styleClasses map[string]string
+
+	// The fonts used while drawing, in the order they were first used, and the runes drawn
+	// with each font
+	fonts     []Font
+	fontRunes map[Font]map[rune]bool
+
+	// The font faces declared by the style sheet, in the order the fonts were first used
+	fontFaces []fontFace
+}
+
+// A font face declared by the style sheet.  Embedded fonts are declared with a family
+// named after the subset, so that the font faces of SVGs inlined within the same page do
+// not replace each other.
+type fontFace struct {
+	font   EmbeddableFont
+	family string
+	src    string
+
+	// The reason the font is left out of the style sheet, or nil if it is declared
+	err error
 }
 
 func NewGraphic(rows, cols int) *Graphic {
@@ -55,6 +112,11 @@ func (g *Graphic) Cols() int {
 	}
 }
 
+// Returns the width and height of the graphic, in unscaled pixels
+func (g *Graphic) Size() (int, int) {
+	return g.remeasure()
+}
+
 // Resize the matrix
 func (g *Graphic) resizeTo(rows, cols int) {
 	newRows := make([][]matrixItem, rows)
@@ -169,61 +231,270 @@ func (g *Graphic) propogateDeltas() {
 // Sets a point in the matrix.  If the point is beyond the scope of the matrix,
 // returns false.
 func (g *Graphic) Put(r, c int, item GraphboxItem) bool {
+	return g.PutWithClass(r, c, item, "")
+}
+
+// Sets a point in the matrix with an item drawn within a group with a class attribute.
+// The class names the parts of the diagram for style sheets.  If the point is beyond the
+// scope of the matrix, returns false.
+func (g *Graphic) PutWithClass(r, c int, item GraphboxItem, class string) bool {
 	if (r >= 0) && (c >= 0) && (r < len(g.matrix)) && (c < len(g.matrix[r])) {
 		//g.matrix[r][c].Item = item
-		g.items = append(g.items, itemInstance{r, c, item})
+		g.items = append(g.items, itemInstance{r, c, item, class})
+		return true
+	} else {
+		return false
+	}
+}
+
+// Returns the number of items put in the graphic
+func (g *Graphic) ItemCount() int {
+	return len(g.items)
+}
+
+// Inserts an item before the item at an index, so that it is drawn beneath the items put
+// after it.  If the point is beyond the scope of the matrix, returns false.
+func (g *Graphic) InsertWithClass(index int, r, c int, item GraphboxItem, class string) bool {
+	if (r >= 0) && (c >= 0) && (r < len(g.matrix)) && (c < len(g.matrix[r])) && (index >= 0) && (index <= len(g.items)) {
+		g.items = append(g.items[:index], append([]itemInstance{{r, c, item, class}}, g.items[index:]...)...)
 		return true
 	} else {
 		return false
 	}
 }
 
+// Returns the height of the items in the rows above a row, including the space these
+// items require below them.  The items from the row onwards are ignored.
+func (g *Graphic) HeightAboveRow(row int) int {
+	above := NewGraphic(g.Rows(), g.Cols())
+	above.Margin = g.Margin
+	for _, item := range g.items {
+		if item.R < row {
+			above.items = append(above.items, item)
+		}
+	}
+
+	_, height := above.remeasure()
+	return height - g.Margin.Y
+}
+
 // Draws the graphics as an SVG
 func (g *Graphic) DrawSVG(w io.Writer) {
 	sizeW, sizeH := g.remeasure()
+	g.drawSVGView(w, sizeW, sizeH, 0, sizeH)
+}
+
+// Draws the part of the graphics between two vertical positions as an SVG.  The
+// entire graphic is drawn but only the part between the positions is visible.
+func (g *Graphic) DrawSVGSlice(w io.Writer, top int, bottom int) {
+	sizeW, sizeH := g.remeasure()
+	g.drawSVGView(w, sizeW, sizeH, top, bottom)
+}
+
+// Writes the font faces and style classes used by the graphic as CSS rules, without the
+// enclosing style element.  Used with OmitStyleSheet.
+func (g *Graphic) WriteStyleSheet(w io.Writer) {
+	sizeW, sizeH := g.remeasure()
+	g.drawItems(sizeW, sizeH)
+	g.writeStyleRules(w)
+}
+
+// Draws the measured graphics as an SVG with a view box between two vertical positions
+func (g *Graphic) drawSVGView(w io.Writer, sizeW int, sizeH int, top int, bottom int) {
+	viewH := bottom - top
+
+	// The items are drawn before the style sheet is written, as the style classes are
+	// only known once the items have been drawn
+	items := g.drawItems(sizeW, sizeH)
 
 	canvas := svg.New(w)
 
 	if g.Viewport {
-		canvas.StartviewUnit(100, 100, "%", 0, 0, sizeW, sizeH)
+		canvas.StartviewUnit(100, 100, "%", 0, top, sizeW, viewH)
+	} else if (g.Scale > 0) && (g.Scale != 1) {
+		canvas.Startview(int(float64(sizeW)*g.Scale), int(float64(viewH)*g.Scale), 0, top, sizeW, viewH)
+	} else if (top != 0) || (bottom != sizeH) {
+		canvas.Startview(sizeW, viewH, 0, top, sizeW, viewH)
 	} else {
 		canvas.Start(sizeW, sizeH)
 	}
 	defer canvas.End()
 
 	// Add styles
-	canvas.Def()
-	g.addStyles(canvas)
-	canvas.DefEnd()
+	if !g.OmitStyleSheet {
+		canvas.Def()
+		fmt.Fprintln(canvas.Writer, "<style>")
+		g.writeStyleRules(canvas.Writer)
+		fmt.Fprintln(canvas.Writer, "</style>")
+		canvas.DefEnd()
+	}
+
+	canvas.Writer.Write(items.Bytes())
+}
+
+// Draws the items of the measured graphics, recording the fonts and style classes used.
+// The items are drawn twice: once to find the glyphs to embed, which name the font faces,
+// and again to draw the text with the families of the font faces.
+func (g *Graphic) drawItems(sizeW int, sizeH int) *bytes.Buffer {
+	g.fontFaces = nil
+	g.drawItemsOnce(sizeW, sizeH)
+	g.fontFaces = g.buildFontFaces()
+	return g.drawItemsOnce(sizeW, sizeH)
+}
+
+// Draws the items of the measured graphics with the current font faces
+func (g *Graphic) drawItemsOnce(sizeW int, sizeH int) *bytes.Buffer {
+	g.styleClasses = make(map[string]string)
+	g.fonts = nil
+	g.fontRunes = make(map[Font]map[rune]bool)
+	items := new(bytes.Buffer)
+	itemCanvas := svg.New(items)
+
+	if g.Background != "" {
+		itemCanvas.Group(`class="background"`)
+		itemCanvas.Rect(0, 0, sizeW, sizeH, g.styleAttr("fill:"+g.Background+";stroke:none;"))
+		itemCanvas.Gend()
+	}
 
 	for _, item := range g.items {
-		g.drawItem(canvas, item)
+		g.drawItem(itemCanvas, item)
 	}
 
 	// Draw the grid.  Used manily for debugging
 	if g.ShowGrid {
 		for _, row := range g.matrix {
 			for _, cell := range row {
-				canvas.Circle(cell.Point.X, cell.Point.Y, 2, "brush:red;stroke:red;")
+				itemCanvas.Circle(cell.Point.X, cell.Point.Y, 2, g.styleAttr("brush:red;stroke:red;"))
 			}
 		}
 	}
+
+	return items
 }
 
-// Add the style definitions, including font faces
-func (g *Graphic) addStyles(canvas *svg.SVG) {
-	fmt.Fprintln(canvas.Writer, "<style>")
+// Writes the style rules, including font faces
+func (g *Graphic) writeStyleRules(w io.Writer) {
+	for _, face := range g.fontFaces {
+		if face.err != nil {
+			fmt.Fprintf(w, "/* %s not embedded: %s */\n", face.font.FamilyName(), strings.Replace(face.err.Error(), "*/", "* /", -1))
+			continue
+		}
 
-	// !!TEMP!!
-	fmt.Fprintln(canvas.Writer, "@font-face {")
-	fmt.Fprintln(canvas.Writer, "  font-family: 'DejaVuSans';")
-	fmt.Fprintln(canvas.Writer, "  src: url('https://fontlibrary.org/assets/fonts/dejavu-sans/f5ec8426554a3a67ebcdd39f9c3fee83/49c0f03ec2fa354df7002bcb6331e106/DejaVuSansBook.ttf') format('truetype');")
-	fmt.Fprintln(canvas.Writer, "  font-weight: normal;")
-	fmt.Fprintln(canvas.Writer, "  font-style: normal;")
-	fmt.Fprintln(canvas.Writer, "}")
-	// !!END TEMP!!
+		fmt.Fprintln(w, "@font-face {")
+		fmt.Fprintf(w, "  font-family: '%s';\n", face.family)
+		fmt.Fprintf(w, "  src: url('%s') format('truetype');\n", face.src)
+		fmt.Fprintf(w, "  font-weight: %s;\n", face.font.Weight())
+		fmt.Fprintf(w, "  font-style: %s;\n", face.font.Style())
+		fmt.Fprintln(w, "}")
+	}
 
-	fmt.Fprintln(canvas.Writer, "</style>")
+	styles := make([]string, 0, len(g.styleClasses))
+	for style := range g.styleClasses {
+		styles = append(styles, style)
+	}
+	sort.Strings(styles)
+
+	for _, style := range styles {
+		fmt.Fprintf(w, ".%s { %s }\n", g.styleClasses[style], style)
+	}
+}
+
+// Builds the font faces of the fonts used, either embedding the glyphs used as a data URI
+// or referencing the font URL.  A font which cannot be subset is left out rather than
+// embedding the entire font.
+func (g *Graphic) buildFontFaces() []fontFace {
+	if g.FontURL == NoFontURL {
+		return nil
+	}
+
+	var faces []fontFace
+	for _, font := range g.fonts {
+		embeddableFont, isEmbeddable := font.(EmbeddableFont)
+		if !isEmbeddable {
+			continue
+		}
+
+		face := fontFace{font: embeddableFont, family: embeddableFont.FamilyName(), src: g.FontURL}
+		if face.src == "" {
+			runes := make([]rune, 0, len(g.fontRunes[font]))
+			for r := range g.fontRunes[font] {
+				runes = append(runes, r)
+			}
+			sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+			subset, err := embeddableFont.Subset(runes)
+			if err != nil {
+				face.err = err
+			} else {
+				hash := fnv.New32a()
+				hash.Write(subset)
+				face.family = fmt.Sprintf("%s-%08x", face.family, hash.Sum32())
+				face.src = "data:font/ttf;base64," + base64.StdEncoding.EncodeToString(subset)
+			}
+		} else if strings.HasSuffix(face.src, "/") {
+			face.src += embeddableFont.FileName()
+		}
+		face.src = fontURLEscaper.Replace(face.src)
+
+		faces = append(faces, face)
+	}
+	return faces
+}
+
+// Returns the CSS font family of text drawn with a font.  Fonts with font faces are named
+// by the family of the font face.
+func (g *Graphic) fontFamily(font Font) string {
+	ttf, isTTF := font.(*TTFFont)
+	if !isTTF {
+		return font.SvgName()
+	}
+
+	return ttf.familyList(func(chainFont *TTFFont) string {
+		for _, face := range g.fontFaces {
+			if (face.font == chainFont) && (face.err == nil) {
+				return face.family
+			}
+		}
+		return chainFont.FamilyName()
+	})
+}
+
+// Records the runes of text drawn with a font, so that the glyphs can be embedded.  The
+// runes drawn by fallback fonts are recorded against the fallback font.
+func (g *Graphic) useFont(font Font, text string) {
+	for _, r := range text {
+		runeFont := font
+		if fallbackFont, hasFallbacks := font.(FallbackFont); hasFallbacks {
+			runeFont = fallbackFont.FontFor(r)
+		}
+
+		runes, hasFont := g.fontRunes[runeFont]
+		if !hasFont {
+			runes = make(map[rune]bool)
+			g.fontRunes[runeFont] = runes
+			g.fonts = append(g.fonts, runeFont)
+		}
+		runes[r] = true
+	}
+}
+
+// Returns the attribute which applies a style to an element.  If the graphic uses style
+// classes, this is a class attribute, otherwise the style is set on the element.  The
+// class is named from a hash of the style so that diagrams embedded within the same page
+// use the same name for the same style.
+func (g *Graphic) styleAttr(style string) string {
+	if !g.StyleClasses {
+		return style
+	}
+
+	class, hasClass := g.styleClasses[style]
+	if !hasClass {
+		hash := fnv.New32a()
+		hash.Write([]byte(style))
+		class = fmt.Sprintf("goseq-%08x", hash.Sum32())
+		g.styleClasses[style] = class
+	}
+	return `class="` + class + `"`
 }
 
 // Draws the item
@@ -233,6 +504,11 @@ func (g *Graphic) drawItem(canvas *svg.SVG, item itemInstance) {
 		return
 	}
 
+	if item.Class != "" {
+		canvas.Group(`class="` + item.Class + `"`)
+		defer canvas.Gend()
+	}
+
 	ctx := DrawContext{canvas, g, item.R, item.C}
 	point := g.matrix[item.R][item.C].Point
 	item.Item.Draw(ctx, point)
@@ -256,6 +532,7 @@ type matrixItem struct {
 }
 
 type itemInstance struct {
-	R, C int
-	Item GraphboxItem
+	R, C  int
+	Item  GraphboxItem
+	Class string
 }
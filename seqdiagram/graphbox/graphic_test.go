@@ -0,0 +1,49 @@
+package graphbox
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Draws two notes with the same style, returning the SVG
+func drawTwoNotes(t *testing.T, styleClasses bool) string {
+	g := NewGraphic(3, 1)
+	g.FontURL = NoFontURL
+	g.StyleClasses = styleClasses
+	g.PutWithClass(1, 0, NewNoteBox("First", testNoteStyle(t), CenterNotePos), "note")
+	g.PutWithClass(2, 0, NewNoteBox("Second", testNoteStyle(t), CenterNotePos), "note")
+
+	out := new(bytes.Buffer)
+	g.DrawSVG(out)
+	return out.String()
+}
+
+func TestGraphicStyleClasses(t *testing.T) {
+	assert := assert.Assert(t)
+
+	svg := drawTwoNotes(t, true)
+	frameStyle := "fill:white;stroke-width:2px;stroke:black;"
+	hash := fnv.New32a()
+	hash.Write([]byte(frameStyle))
+	class := fmt.Sprintf("goseq-%08x", hash.Sum32())
+
+	assert.Equal(strings.Count(svg, `<g class="note" >`), 2)
+	assert.Equal(strings.Count(svg, `class="`+class+`"`), 2)
+	assert.Equal(strings.Count(svg, "."+class+" { "+frameStyle+" }"), 1)
+	assert.Equal(strings.Contains(svg, ` style="`), false)
+}
+
+func TestGraphicInlineStyles(t *testing.T) {
+	assert := assert.Assert(t)
+
+	svg := drawTwoNotes(t, false)
+
+	assert.Equal(strings.Count(svg, `<g class="note" >`), 2)
+	assert.Equal(strings.Count(svg, `style="fill:white;stroke-width:2px;stroke:black;"`), 2)
+	assert.Equal(strings.Contains(svg, "goseq-"), false)
+}
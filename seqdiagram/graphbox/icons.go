@@ -35,7 +35,7 @@ func (spi StickPersonIcon) Size() (width int, height int) {
 }
 
 func (spi StickPersonIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
-	style := lineStyle.ToStyle()
+	style := ctx.Style(lineStyle.ToStyle())
 
 	_, h := spi.Size()
 	ty := y - h/2
@@ -72,7 +72,7 @@ func (ci CylinderIcon) Size() (width int, height int) {
 
 func (ci CylinderIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
 	//	style := "stroke:black;fill:white;stroke-width:2px;"
-	style := lineStyle.ToStyle()
+	style := ctx.Style(lineStyle.ToStyle())
 
 	leftX, rightX := x-cylinderLargeRadius, x+cylinderLargeRadius
 	upperEllipseY := y - cylinderHeight/2
@@ -97,3 +97,180 @@ func (ci CylinderIcon) drawCurve(ctx DrawContext, fx, fy, tx, ty, mag int, style
 
 	ctx.Canvas.Path(pathCmds.String(), style)
 }
+
+// The UML robustness icons: boundary, control and entity
+//
+
+const robustnessIconRadius = 14
+const boundaryIconStem = 10
+
+type BoundaryIcon int
+
+func (bi BoundaryIcon) Size() (width int, height int) {
+	width = (robustnessIconRadius + boundaryIconStem) * 2
+	height = robustnessIconRadius * 2
+	return
+}
+
+func (bi BoundaryIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+
+	// The circle is offset to the right to balance the stem on the left
+	cx := x + boundaryIconStem/2
+	stemX := cx - robustnessIconRadius - boundaryIconStem
+
+	ctx.Canvas.Line(stemX, y-robustnessIconRadius, stemX, y+robustnessIconRadius, style)
+	ctx.Canvas.Line(stemX, y, cx-robustnessIconRadius, y, style)
+	ctx.Canvas.Circle(cx, y, robustnessIconRadius, style)
+}
+
+type ControlIcon int
+
+func (ci ControlIcon) Size() (width int, height int) {
+	width = robustnessIconRadius*2 + 4
+	height = robustnessIconRadius*2 + 8
+	return
+}
+
+func (ci ControlIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+	cy := y + 2
+
+	ctx.Canvas.Circle(x, cy, robustnessIconRadius, style)
+
+	// The arrow head at the top of the circle
+	topY := cy - robustnessIconRadius
+	ctx.Canvas.Polyline([]int{x + 6, x, x + 6}, []int{topY - 6, topY, topY + 6}, style)
+}
+
+type EntityIcon int
+
+func (ei EntityIcon) Size() (width int, height int) {
+	width = robustnessIconRadius*2 + 4
+	height = robustnessIconRadius*2 + 6
+	return
+}
+
+func (ei EntityIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+	cy := y - 3
+
+	ctx.Canvas.Circle(x, cy, robustnessIconRadius, style)
+
+	lineY := cy + robustnessIconRadius + 3
+	ctx.Canvas.Line(x-robustnessIconRadius-2, lineY, x+robustnessIconRadius+2, lineY, style)
+}
+
+// A horizontal cylinder suggesting a queue
+//
+
+const queueIconLength = 40
+const queueIconRadius = 12
+const queueIconEndRadius = 5
+
+type QueueIcon int
+
+func (qi QueueIcon) Size() (width int, height int) {
+	width = queueIconLength + queueIconEndRadius*2
+	height = queueIconRadius * 2
+	return
+}
+
+func (qi QueueIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+
+	leftX, rightX := x-queueIconLength/2, x+queueIconLength/2
+	topY, bottomY := y-queueIconRadius, y+queueIconRadius
+
+	ctx.Canvas.Line(leftX, topY, rightX, topY, style)
+	ctx.Canvas.Line(leftX, bottomY, rightX, bottomY, style)
+	ctx.Canvas.Ellipse(leftX, y, queueIconEndRadius, queueIconRadius, style)
+
+	// Only the outer half of the right end is visible
+	pathCmds := new(bytes.Buffer)
+	fmt.Fprintf(pathCmds, "M%d,%d A%d,%d 0 0,1 %d,%d", rightX, topY, queueIconEndRadius, queueIconRadius, rightX, bottomY)
+	ctx.Canvas.Path(pathCmds.String(), style)
+}
+
+// A stack of rectangles suggesting a collection of objects
+//
+
+const collectionsIconWidth = 32
+const collectionsIconHeight = 22
+const collectionsIconOffset = 5
+
+type CollectionsIcon int
+
+func (ci CollectionsIcon) Size() (width int, height int) {
+	width = collectionsIconWidth + collectionsIconOffset
+	height = collectionsIconHeight + collectionsIconOffset
+	return
+}
+
+func (ci CollectionsIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+
+	w, h := ci.Size()
+	leftX, topY := x-w/2, y-h/2
+
+	ctx.Canvas.Rect(leftX+collectionsIconOffset, topY, collectionsIconWidth, collectionsIconHeight, style)
+	ctx.Canvas.Rect(leftX, topY+collectionsIconOffset, collectionsIconWidth, collectionsIconHeight, style)
+}
+
+// The UML component icon
+//
+
+const componentIconWidth = 32
+const componentIconHeight = 28
+const componentIconTabWidth = 12
+const componentIconTabHeight = 6
+
+type ComponentIcon int
+
+func (ci ComponentIcon) Size() (width int, height int) {
+	width = componentIconWidth + componentIconTabWidth/2
+	height = componentIconHeight
+	return
+}
+
+func (ci ComponentIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+
+	w, h := ci.Size()
+	bodyX, topY := x-w/2+componentIconTabWidth/2, y-h/2
+	tabX := bodyX - componentIconTabWidth/2
+
+	ctx.Canvas.Rect(bodyX, topY, componentIconWidth, componentIconHeight, style)
+	ctx.Canvas.Rect(tabX, topY+componentIconHeight/4-componentIconTabHeight/2, componentIconTabWidth, componentIconTabHeight, style)
+	ctx.Canvas.Rect(tabX, topY+componentIconHeight*3/4-componentIconTabHeight/2, componentIconTabWidth, componentIconTabHeight, style)
+}
+
+// A cloud suggesting an external service
+//
+
+const cloudIconWidth = 44
+const cloudIconHeight = 28
+
+type CloudIcon int
+
+func (ci CloudIcon) Size() (width int, height int) {
+	return cloudIconWidth, cloudIconHeight
+}
+
+func (ci CloudIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	style := ctx.Style(lineStyle.ToStyle())
+
+	leftX, rightX := x-cloudIconWidth/2, x+cloudIconWidth/2
+	bottomY := y + cloudIconHeight/2
+
+	// A flat base with three bumps of increasing and decreasing size
+	pathCmds := new(bytes.Buffer)
+	fmt.Fprintf(pathCmds, "M%d,%d ", leftX+6, bottomY)
+	fmt.Fprintf(pathCmds, "A8,8 0 0,1 %d,%d ", leftX+8, bottomY-14)
+	fmt.Fprintf(pathCmds, "A12,12 0 0,1 %d,%d ", x+8, y-8)
+	fmt.Fprintf(pathCmds, "A9,9 0 0,1 %d,%d ", rightX-4, bottomY-4)
+	fmt.Fprintf(pathCmds, "A4,4 0 0,1 %d,%d ", rightX-8, bottomY)
+	fmt.Fprint(pathCmds, "Z")
+
+	ctx.Canvas.Path(pathCmds.String(), style)
+}
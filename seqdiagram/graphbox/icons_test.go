@@ -0,0 +1,91 @@
+package graphbox
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/ajstarks/svgo"
+)
+
+// Returns the bounds of the points of the elements drawn on an SVG canvas.  The points
+// are the ends of lines and paths, the corners of rectangles and the extents of circles
+// and ellipses.
+func drawnBounds(t *testing.T, svgText string) Rect {
+	minX, minY, maxX, maxY := 1<<30, 1<<30, -1<<30, -1<<30
+	addPoint := func(x, y int) {
+		minX, minY, maxX, maxY = minInt(minX, x), minInt(minY, y), maxInt(maxX, x), maxInt(maxY, y)
+	}
+	attr := func(element, name string) int {
+		match := regexp.MustCompile(`\s` + name + `="(-?\d+)"`).FindStringSubmatch(element)
+		if match == nil {
+			t.Fatalf("no %s attribute in %s", name, element)
+		}
+		n, _ := strconv.Atoi(match[1])
+		return n
+	}
+	addPoints := func(coords string) {
+		numbers := regexp.MustCompile(`-?\d+`).FindAllString(coords, -1)
+		for i := 0; i+1 < len(numbers); i += 2 {
+			x, _ := strconv.Atoi(numbers[i])
+			y, _ := strconv.Atoi(numbers[i+1])
+			addPoint(x, y)
+		}
+	}
+
+	for _, element := range regexp.MustCompile(`<(line|rect|circle|ellipse|polyline|polygon|path)\s[^>]*>`).FindAllString(svgText, -1) {
+		switch regexp.MustCompile(`^<(\w+)`).FindStringSubmatch(element)[1] {
+		case "line":
+			addPoint(attr(element, "x1"), attr(element, "y1"))
+			addPoint(attr(element, "x2"), attr(element, "y2"))
+		case "rect":
+			x, y := attr(element, "x"), attr(element, "y")
+			addPoint(x, y)
+			addPoint(x+attr(element, "width"), y+attr(element, "height"))
+		case "circle":
+			cx, cy, r := attr(element, "cx"), attr(element, "cy"), attr(element, "r")
+			addPoint(cx-r, cy-r)
+			addPoint(cx+r, cy+r)
+		case "ellipse":
+			cx, cy, rx, ry := attr(element, "cx"), attr(element, "cy"), attr(element, "rx"), attr(element, "ry")
+			addPoint(cx-rx, cy-ry)
+			addPoint(cx+rx, cy+ry)
+		case "polyline", "polygon":
+			addPoints(regexp.MustCompile(`points="([^"]*)"`).FindStringSubmatch(element)[1])
+		case "path":
+			// Only the move and the end points of the arcs
+			path := regexp.MustCompile(`d="([^"]*)"`).FindStringSubmatch(element)[1]
+			for _, cmd := range regexp.MustCompile(`[MA][^MAZ]*`).FindAllString(path, -1) {
+				numbers := regexp.MustCompile(`-?\d+`).FindAllString(cmd, -1)
+				addPoints(numbers[len(numbers)-2] + "," + numbers[len(numbers)-1])
+			}
+		}
+	}
+	return Rect{minX, minY, maxX - minX, maxY - minY}
+}
+
+func TestIconsDrawnWithinSize(t *testing.T) {
+	for name, icon := range map[string]Icon{
+		"boundary":    BoundaryIcon(1),
+		"control":     ControlIcon(1),
+		"entity":      EntityIcon(1),
+		"queue":       QueueIcon(1),
+		"collections": CollectionsIcon(1),
+		"component":   ComponentIcon(1),
+		"cloud":       CloudIcon(1),
+	} {
+		out := new(bytes.Buffer)
+		ctx := DrawContext{Canvas: svg.New(out), Graphic: NewGraphic(1, 1)}
+		icon.Draw(ctx, 100, 100, &SvgStyle{"stroke": "black"})
+
+		w, h := icon.Size()
+		bounds := drawnBounds(t, out.String())
+		if (bounds.W == 0) || (bounds.H == 0) {
+			t.Errorf("%s: nothing drawn", name)
+		} else if (bounds.X < 100-w/2) || (bounds.Y < 100-h/2) ||
+			(bounds.X+bounds.W > 100+(w+1)/2) || (bounds.Y+bounds.H > 100+(h+1)/2) {
+			t.Errorf("%s: drawn within %v, outside of the size %dx%d centered at 100,100", name, bounds, w, h)
+		}
+	}
+}
@@ -15,7 +15,7 @@ func (ll *LifeLine) Constraint(r, c int, applier ConstraintApplier) {
 
 func (ll *LifeLine) Draw(ctx DrawContext, point Point) {
 	s := SvgStyle{}
-	s.Set("stroke", ll.Style.Color)
+	s.Set("stroke", colorOr(ll.Style.Color, "black"))
 	s.Set("stroke-dasharray", "8,8")
 	s.Set("stroke-width", "2px")
 
@@ -23,6 +23,6 @@ func (ll *LifeLine) Draw(ctx DrawContext, point Point) {
 	if point, isPoint := ctx.PointAt(ll.TR, ll.TC); isPoint {
 		tx, ty := point.X, point.Y
 
-		ctx.Canvas.Line(fx, fy, tx, ty, s.ToStyle())
+		ctx.Canvas.Line(fx, fy, tx, ty, ctx.Style(s.ToStyle()))
 	}
 }
@@ -1,5 +1,10 @@
 package graphbox
 
+import (
+	"bytes"
+	"fmt"
+)
+
 type NoteBoxPos int
 
 const (
@@ -8,6 +13,32 @@ const (
 	RightNotePos             = iota
 )
 
+// NoteBoxShape is the shape of the note frame
+type NoteBoxShape int
+
+const (
+	// RectNoteShape is a plain rectangle
+	RectNoteShape NoteBoxShape = iota
+
+	// FoldedNoteShape is the UML note shape, a rectangle with a folded top-right corner
+	FoldedNoteShape
+
+	// RoundedNoteShape is a rectangle with rounded corners
+	RoundedNoteShape
+
+	// HexagonNoteShape is a hexagon with points on the left and right
+	HexagonNoteShape
+
+	// CloudNoteShape is a rectangle with a scalloped outline
+	CloudNoteShape
+)
+
+const (
+	noteFoldSize     = 8
+	noteCornerRadius = 8
+	noteCloudBump    = 12
+)
+
 // Styling options for the actor rect
 type NoteBoxStyle struct {
 	Font     Font
@@ -15,6 +46,11 @@ type NoteBoxStyle struct {
 	Padding  Point
 	Margin   Point
 	Position NoteBoxPos
+
+	Shape     NoteBoxShape
+	Color     string
+	FillColor string
+	TextColor string
 }
 
 // Draws an object instance
@@ -26,15 +62,27 @@ type NoteBox struct {
 }
 
 func NewNoteBox(text string, style NoteBoxStyle, pos NoteBoxPos) *NoteBox {
-	var textAlign TextAlign = MiddleTextAlign
+	textBox, brect := newNoteTextBox(text, style)
+	return &NoteBox{brect, style, textBox, pos}
+}
 
-	textBox := NewTextBox(style.Font, style.FontSize, textAlign)
+// Returns the text box of a note, along with the size of the frame.  Shapes which
+// cut into the corners of the frame are given extra room.
+func newNoteTextBox(text string, style NoteBoxStyle) (*TextBox, Rect) {
+	textBox := NewTextBox(style.Font, style.FontSize, MiddleTextAlign)
+	textBox.Color = style.TextColor
 	textBox.AddText(text)
 
-	trect := textBox.BoundingRect()
-	brect := trect.BlowOut(style.Padding)
-
-	return &NoteBox{brect, style, textBox, pos}
+	brect := textBox.BoundingRect().BlowOut(style.Padding)
+	switch style.Shape {
+	case FoldedNoteShape:
+		brect = brect.BlowOut(Point{noteFoldSize / 2, 0})
+	case HexagonNoteShape:
+		brect = brect.BlowOut(Point{brect.H / 3, 0})
+	case CloudNoteShape:
+		brect = brect.BlowOut(Point{noteCloudBump / 2, noteCloudBump / 2})
+	}
+	return textBox, brect
 }
 
 func (tr *NoteBox) Constraint(r, c int, applier ConstraintApplier) {
@@ -58,21 +106,185 @@ func (r *NoteBox) Draw(ctx DrawContext, point Point) {
 	centerX, centerY := point.X, point.Y
 	marginX := r.style.Margin.X
 
-	if r.pos == CenterNotePos {
-		rect := r.frameRect.PositionAt(centerX, centerY, CenterGravity)
-		ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, "stroke:black;fill:white;stroke-width:2px;")
-		r.textBox.Render(ctx.Canvas, centerX, centerY, CenterGravity)
-	} else if r.pos == LeftNotePos {
-		offsetX := centerX - marginX
-		textOffsetX := centerX - r.style.Padding.X - marginX
-		rect := r.frameRect.PositionAt(offsetX, centerY, EastGravity)
-		ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, "stroke:black;fill:white;stroke-width:2px;")
-		r.textBox.Render(ctx.Canvas, textOffsetX, centerY, EastGravity)
+	var rect Rect
+	if r.pos == LeftNotePos {
+		rect = r.frameRect.PositionAt(centerX-marginX, centerY, EastGravity)
 	} else if r.pos == RightNotePos {
-		offsetX := centerX + marginX
-		textOffsetX := centerX + r.style.Padding.X + marginX
-		rect := r.frameRect.PositionAt(offsetX, centerY, WestGravity)
-		ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, "stroke:black;fill:white;stroke-width:2px;")
-		r.textBox.Render(ctx.Canvas, textOffsetX, centerY, WestGravity)
+		rect = r.frameRect.PositionAt(centerX+marginX, centerY, WestGravity)
+	} else {
+		rect = r.frameRect.PositionAt(centerX, centerY, CenterGravity)
 	}
+
+	drawNoteFrame(ctx, rect, r.style)
+	r.textBox.Render(ctx, rect.X+rect.W/2, centerY, CenterGravity)
+}
+
+// Draws a note which spans the lifelines between two columns.  The note overlaps the
+// lifeline on a side with the center position, or stops short of the lifeline on the
+// opposite side with the left or right position.
+type SpanNoteBox struct {
+	TC int
+
+	overlap    int
+	leftExtra  int
+	rightExtra int
+
+	frameRect Rect
+	style     NoteBoxStyle
+	textBox   *TextBox
+	pos       NoteBoxPos
+}
+
+func NewSpanNoteBox(toCol int, overlap int, text string, style NoteBoxStyle, pos NoteBoxPos) *SpanNoteBox {
+	textBox, brect := newNoteTextBox(text, style)
+	return &SpanNoteBox{toCol, overlap, 0, 0, brect, style, textBox, pos}
+}
+
+func (tr *SpanNoteBox) Constraint(r, c int, applier ConstraintApplier) {
+	marginX := tr.style.Margin.X
+	marginY := tr.style.Margin.Y
+
+	// The distance the note extends beyond the lifelines.  This is negative for notes
+	// which stop short of the lifeline.
+	tr.leftExtra, tr.rightExtra = tr.overlap, tr.overlap
+	if tr.pos == RightNotePos {
+		tr.leftExtra = -marginX
+	} else if tr.pos == LeftNotePos {
+		tr.rightExtra = -marginX
+	}
+
+	// Notes do not extend beyond the edges of the graphic
+	if c == 0 {
+		tr.leftExtra = minInt(tr.leftExtra, 0)
+	}
+	if tr.TC == applier.Cols()-1 {
+		tr.rightExtra = minInt(tr.rightExtra, 0)
+	}
+
+	if tr.leftExtra > 0 {
+		applier.Apply(SizeConstraint{r, c, tr.leftExtra + marginX, 0, 0, 0})
+	}
+	if tr.rightExtra > 0 {
+		applier.Apply(SizeConstraint{r, tr.TC, 0, tr.rightExtra + marginX, 0, 0})
+	}
+	applier.Apply(TotalSizeConstraint{r - 1, c, r, tr.TC, tr.frameRect.W - tr.leftExtra - tr.rightExtra, 0})
+	applier.Apply(AddSizeConstraint{r, c, 0, 0, tr.frameRect.H/2 + marginY, tr.frameRect.H/2 + marginY})
+}
+
+func (tr *SpanNoteBox) Draw(ctx DrawContext, point Point) {
+	if toPoint, isPoint := ctx.PointAt(ctx.R, tr.TC); isPoint {
+		fx, tx := point.X-tr.leftExtra, toPoint.X+tr.rightExtra
+		centerX, centerY := fx+(tx-fx)/2, point.Y
+
+		rect := Rect{fx, centerY - tr.frameRect.H/2, tx - fx, tr.frameRect.H}
+		drawNoteFrame(ctx, rect, tr.style)
+		tr.textBox.Render(ctx, centerX, centerY, CenterGravity)
+	}
+}
+
+// Draws the frame of a note in the shape of the style
+func drawNoteFrame(ctx DrawContext, rect Rect, style NoteBoxStyle) {
+	s := SvgStyle{}
+	s.Set("stroke", colorOr(style.Color, "black"))
+	s.Set("fill", colorOr(style.FillColor, "white"))
+	s.Set("stroke-width", "2px")
+
+	x, y, w, h := rect.X, rect.Y, rect.W, rect.H
+	switch style.Shape {
+	case FoldedNoteShape:
+		fold := noteFoldSize
+		ctx.Canvas.Polygon(
+			[]int{x, x + w - fold, x + w, x + w, x},
+			[]int{y, y, y + fold, y + h, y + h},
+			ctx.Style(s.ToStyle()))
+		ctx.Canvas.Polyline(
+			[]int{x + w - fold, x + w - fold, x + w},
+			[]int{y, y + fold, y + fold},
+			ctx.Style(s.ToStyle()))
+	case RoundedNoteShape:
+		ctx.Canvas.Roundrect(x, y, w, h, noteCornerRadius, noteCornerRadius, ctx.Style(s.ToStyle()))
+	case HexagonNoteShape:
+		point := h / 3
+		ctx.Canvas.Polygon(
+			[]int{x, x + point, x + w - point, x + w, x + w - point, x + point},
+			[]int{y + h/2, y, y, y + h/2, y + h, y + h},
+			ctx.Style(s.ToStyle()))
+	case CloudNoteShape:
+		ctx.Canvas.Path(cloudPath(rect), ctx.Style(s.ToStyle()))
+	default:
+		ctx.Canvas.Rect(x, y, w, h, ctx.Style(s.ToStyle()))
+	}
+}
+
+// Returns the path of a cloud outline within the rectangle.  The outline is made up of
+// arcs bulging out from a rectangle inset from the edges.
+func cloudPath(rect Rect) string {
+	inset := noteCloudBump / 2
+	inner := rect.BlowOut(Point{-inset, -inset})
+
+	// Divide each side into an equal number of bumps
+	bumpsX := maxInt(inner.W/noteCloudBump, 1)
+	bumpsY := maxInt(inner.H/noteCloudBump, 1)
+
+	pathCmds := new(bytes.Buffer)
+	fmt.Fprint(pathCmds, "M", inner.X, inner.Y, " ")
+
+	arcTo := func(fromX, fromY, toX, toY int) {
+		radius := maxInt(absInt(toX-fromX), absInt(toY-fromY))/2 + 1
+		fmt.Fprintf(pathCmds, "A%d,%d 0 0,1 %d,%d ", radius, radius, toX, toY)
+	}
+
+	for i := 0; i < bumpsX; i++ {
+		arcTo(inner.X+inner.W*i/bumpsX, inner.Y, inner.X+inner.W*(i+1)/bumpsX, inner.Y)
+	}
+	for i := 0; i < bumpsY; i++ {
+		arcTo(inner.X+inner.W, inner.Y+inner.H*i/bumpsY, inner.X+inner.W, inner.Y+inner.H*(i+1)/bumpsY)
+	}
+	for i := 0; i < bumpsX; i++ {
+		arcTo(inner.X+inner.W*(bumpsX-i)/bumpsX, inner.Y+inner.H, inner.X+inner.W*(bumpsX-i-1)/bumpsX, inner.Y+inner.H)
+	}
+	for i := 0; i < bumpsY; i++ {
+		arcTo(inner.X, inner.Y+inner.H*(bumpsY-i)/bumpsY, inner.X, inner.Y+inner.H*(bumpsY-i-1)/bumpsY)
+	}
+
+	fmt.Fprint(pathCmds, "Z")
+	return pathCmds.String()
+}
+
+// Draws a note beside the end of an activity line on the same row.  The note is joined
+// to the end of the line with a connector.
+type MessageNoteBox struct {
+	offsetX   int
+	frameRect Rect
+	style     NoteBoxStyle
+	textBox   *TextBox
+}
+
+// NewMessageNoteBox returns a new message note.  The offset is the distance of the
+// end of the activity line from the lifeline of the column.
+func NewMessageNoteBox(offsetX int, text string, style NoteBoxStyle) *MessageNoteBox {
+	textBox, brect := newNoteTextBox(text, style)
+	return &MessageNoteBox{offsetX, brect, style, textBox}
+}
+
+func (tr *MessageNoteBox) Constraint(r, c int, applier ConstraintApplier) {
+	marginX := tr.style.Margin.X
+	marginY := tr.style.Margin.Y
+
+	right := 0
+	if c < applier.Cols()-1 {
+		right = tr.offsetX + marginX*2 + tr.frameRect.W + marginX
+	}
+	applier.Apply(SizeConstraint{r, c, 0, right, tr.frameRect.H/2 + marginY, tr.frameRect.H/2 + marginY})
+}
+
+func (tr *MessageNoteBox) Draw(ctx DrawContext, point Point) {
+	lineX, centerY := point.X+tr.offsetX, point.Y
+	noteX := lineX + tr.style.Margin.X*2
+
+	ctx.Canvas.Line(lineX, centerY, noteX, centerY, ctx.Style("stroke:"+colorOr(tr.style.Color, "black")+";stroke-dasharray:2,2;stroke-width:1px;"))
+
+	rect := tr.frameRect.PositionAt(noteX, centerY, WestGravity)
+	drawNoteFrame(ctx, rect, tr.style)
+	tr.textBox.Render(ctx, rect.X+rect.W/2, centerY, CenterGravity)
 }
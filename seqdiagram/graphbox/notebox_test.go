@@ -0,0 +1,129 @@
+package graphbox
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Returns the style of a note drawn with Go Regular
+func testNoteStyle(t *testing.T) NoteBoxStyle {
+	font, err := NewTTFFontFromByteSlice(goregular.TTF, "Go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NoteBoxStyle{Font: font, FontSize: 14, Padding: Point{8, 4}, Margin: Point{8, 8}}
+}
+
+// Draws an item on the middle row of a graphic with three rows, returning the graphic
+// and the SVG
+func drawTestItem(cols, col int, item GraphboxItem) (*Graphic, string) {
+	g := NewGraphic(3, cols)
+	g.FontURL = NoFontURL
+	g.Put(1, col, item)
+
+	out := new(bytes.Buffer)
+	g.DrawSVG(out)
+	return g, out.String()
+}
+
+func TestSpanNoteBoxExtents(t *testing.T) {
+	assert := assert.Assert(t)
+	style := testNoteStyle(t)
+
+	for _, test := range []struct {
+		cols, col, toCol      int
+		pos                   NoteBoxPos
+		leftExtra, rightExtra int
+	}{
+		{4, 1, 2, CenterNotePos, 10, 10},
+		{4, 1, 2, RightNotePos, -8, 10},
+		{4, 1, 2, LeftNotePos, 10, -8},
+		{2, 0, 1, CenterNotePos, 0, 0},
+		{2, 0, 1, RightNotePos, -8, 0},
+	} {
+		note := NewSpanNoteBox(test.toCol, 10, "Over both", style, test.pos)
+		g, _ := drawTestItem(test.cols, test.col, note)
+
+		assert.Equal(note.leftExtra, test.leftExtra)
+		assert.Equal(note.rightExtra, test.rightExtra)
+
+		// The columns are wide enough for the note
+		from, _ := g.PointAt(1, test.col)
+		to, _ := g.PointAt(1, test.toCol)
+		assert.Equal(to.X-from.X+note.leftExtra+note.rightExtra >= note.frameRect.W, true)
+	}
+}
+
+func TestNoteBoxShapes(t *testing.T) {
+	assert := assert.Assert(t)
+	style := testNoteStyle(t)
+	rectFrame := NewNoteBox("Note", style, CenterNotePos).frameRect
+
+	for _, test := range []struct {
+		shape      NoteBoxShape
+		element    string
+		extraWidth int
+	}{
+		{RectNoteShape, "<rect ", 0},
+		{FoldedNoteShape, "<polyline ", noteFoldSize},
+		{RoundedNoteShape, `rx="8"`, 0},
+		{HexagonNoteShape, "<polygon ", rectFrame.H / 3 * 2},
+		{CloudNoteShape, "<path ", noteCloudBump},
+	} {
+		style.Shape = test.shape
+		note := NewNoteBox("Note", style, CenterNotePos)
+		_, svg := drawTestItem(1, 0, note)
+
+		assert.Equal(strings.Contains(svg, test.element), true)
+		assert.Equal(note.frameRect.W, rectFrame.W+test.extraWidth)
+	}
+}
+
+func TestNoteBoxColors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	style := testNoteStyle(t)
+	_, svg := drawTestItem(1, 0, NewNoteBox("Note", style, CenterNotePos))
+	assert.Equal(strings.Contains(svg, `style="fill:white;stroke-width:2px;stroke:black;"`), true)
+
+	style.Color, style.FillColor, style.TextColor = "navy", "#ffe", "green"
+	_, svg = drawTestItem(1, 0, NewNoteBox("Note", style, CenterNotePos))
+	assert.Equal(strings.Contains(svg, `style="fill:#ffe;stroke-width:2px;stroke:navy;"`), true)
+	assert.Equal(strings.Contains(svg, "fill:green"), true)
+}
+
+func TestCloudPath(t *testing.T) {
+	assert := assert.Assert(t)
+
+	path := cloudPath(Rect{0, 0, 60, 36})
+
+	// The inset rectangle is 48 by 24, giving four bumps along the top and bottom and two
+	// along the sides
+	assert.Equal(strings.HasPrefix(path, "M6 6 "), true)
+	assert.Equal(strings.HasSuffix(path, "Z"), true)
+	assert.Equal(strings.Count(path, "A"), 12)
+	assert.Equal(strings.Contains(path, "A7,7 0 0,1 18,6 "), true)
+}
+
+func TestMessageNoteBoxConnector(t *testing.T) {
+	assert := assert.Assert(t)
+	style := testNoteStyle(t)
+
+	for _, offsetX := range []int{0, 24} {
+		note := NewMessageNoteBox(offsetX, "Note", style)
+		g, svg := drawTestItem(2, 0, note)
+
+		// The connector runs from the end of the line to the note, two margins away
+		point, _ := g.PointAt(1, 0)
+		lineX := point.X + offsetX
+		connector := fmt.Sprintf(`<line x1="%d" y1="%d" x2="%d" y2="%d" style="stroke:black;stroke-dasharray:2,2;stroke-width:1px;" />`,
+			lineX, point.Y, lineX+16, point.Y)
+		assert.Equal(strings.Contains(svg, connector), true)
+		assert.Equal(strings.Contains(svg, fmt.Sprintf(`<rect x="%d" `, lineX+16)), true)
+	}
+}
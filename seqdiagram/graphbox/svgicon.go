@@ -0,0 +1,219 @@
+package graphbox
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// The maximum width or height of an SVG icon.  Larger images are scaled down to fit.
+const maxSVGIconSize = 48
+
+// Patterns matching the id attributes of the elements of an image, and the references
+// to the ids in URLs and links
+var (
+	svgIDAttrPattern    = regexp.MustCompile(`(\sid\s*=\s*["'])([^"']*)`)
+	svgIDURLPattern     = regexp.MustCompile(`(url\(\s*['"]?#)([^'")\s]*)`)
+	svgIDHrefPattern    = regexp.MustCompile(`(href\s*=\s*["']#)([^"']*)`)
+	svgIDPatternMatches = []*regexp.Regexp{svgIDAttrPattern, svgIDURLPattern, svgIDHrefPattern}
+)
+
+// The number of SVG icons drawn, used to give the ids of each drawn icon a unique prefix
+var svgIconInstances uint64
+
+// An icon drawn from an SVG image.  The image is inlined as a group which is
+// transformed to scale the view box of the image to the size of the icon.
+type SVGIcon struct {
+	width  int
+	height int
+
+	// The view box of the image
+	viewX, viewY, viewW, viewH float64
+
+	// The namespace declarations of the image, and the content of the root element
+	// without any script elements
+	namespaces []xml.Attr
+	content    []byte
+
+	// The ids of the elements of the image, and the hash of the content used to
+	// prefix the ids
+	ids         map[string]bool
+	contentHash uint32
+}
+
+// NewSVGIcon reads an SVG image as an icon.  The size of the icon is determined from
+// the width and height of the image, or the view box if these are not set.
+func NewSVGIcon(r io.Reader) (*SVGIcon, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root xml.StartElement
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, errors.New("Not an SVG image: " + err.Error())
+		} else if start, isStart := token.(xml.StartElement); isStart {
+			root = start
+			break
+		}
+	}
+	if root.Name.Local != "svg" {
+		return nil, errors.New("Not an SVG image: root element is " + root.Name.Local)
+	}
+
+	icon := &SVGIcon{ids: make(map[string]bool)}
+	icon.content, err = icon.readContent(decoder, data)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := fnv.New32a()
+	hash.Write(icon.content)
+	icon.contentHash = hash.Sum32()
+
+	var width, height float64
+	var viewBox string
+	for _, attr := range root.Attr {
+		switch {
+		case (attr.Name.Space == "xmlns") || (attr.Name.Space == "" && attr.Name.Local == "xmlns"):
+			icon.namespaces = append(icon.namespaces, attr)
+		case attr.Name.Local == "width":
+			width = parseSVGLength(attr.Value)
+		case attr.Name.Local == "height":
+			height = parseSVGLength(attr.Value)
+		case attr.Name.Local == "viewBox":
+			viewBox = attr.Value
+		}
+	}
+
+	// Use the view box for the dimensions which are not set
+	_, err = fmt.Sscan(strings.Replace(viewBox, ",", " ", -1), &icon.viewX, &icon.viewY, &icon.viewW, &icon.viewH)
+	if err != nil {
+		icon.viewX, icon.viewY, icon.viewW, icon.viewH = 0, 0, width, height
+	}
+	if width <= 0 {
+		width = icon.viewW
+	}
+	if height <= 0 {
+		height = icon.viewH
+	}
+
+	if (width <= 0) || (height <= 0) || (icon.viewW <= 0) || (icon.viewH <= 0) {
+		return nil, errors.New("SVG image has no size")
+	}
+
+	if scale := maxSVGIconSize / maxFloat(width, height); scale < 1 {
+		width, height = width*scale, height*scale
+	}
+	icon.width, icon.height = int(width+0.5), int(height+0.5)
+
+	return icon, nil
+}
+
+// Reads the content of the root element of the image and the ids of its elements.
+// Script elements are removed from the content.
+func (si *SVGIcon) readContent(decoder *xml.Decoder, data []byte) ([]byte, error) {
+	content := new(bytes.Buffer)
+	start := int(decoder.InputOffset())
+	depth := 0
+
+	for {
+		offset := int(decoder.InputOffset())
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, errors.New("Not an SVG image: " + err.Error())
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "script" {
+				content.Write(data[start:offset])
+				if err := decoder.Skip(); err != nil {
+					return nil, errors.New("Not an SVG image: " + err.Error())
+				}
+				start = int(decoder.InputOffset())
+				continue
+			}
+
+			for _, attr := range t.Attr {
+				if (attr.Name.Space == "") && (attr.Name.Local == "id") {
+					si.ids[attr.Value] = true
+				}
+			}
+			depth++
+		case xml.EndElement:
+			if depth == 0 {
+				content.Write(data[start:offset])
+				return content.Bytes(), nil
+			}
+			depth--
+		}
+	}
+}
+
+// Parses an SVG length in pixels.  Returns 0 if the length is not a pixel length.
+func parseSVGLength(length string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(length), "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func (si *SVGIcon) Size() (width int, height int) {
+	return si.width, si.height
+}
+
+func (si *SVGIcon) Draw(ctx DrawContext, x int, y int, lineStyle *SvgStyle) {
+	w := ctx.Canvas.Writer
+
+	fmt.Fprintf(w, `<g transform="translate(%d,%d) scale(%g,%g)`,
+		x-si.width/2, y-si.height/2, float64(si.width)/si.viewW, float64(si.height)/si.viewH)
+	if (si.viewX != 0) || (si.viewY != 0) {
+		fmt.Fprintf(w, ` translate(%g,%g)`, -si.viewX, -si.viewY)
+	}
+	fmt.Fprint(w, `"`)
+	for _, ns := range si.namespaces {
+		if ns.Name.Space == "xmlns" {
+			fmt.Fprintf(w, ` xmlns:%s="%s"`, ns.Name.Local, ns.Value)
+		} else {
+			fmt.Fprintf(w, ` xmlns="%s"`, ns.Value)
+		}
+	}
+	fmt.Fprint(w, ">")
+	w.Write(si.prefixedContent())
+	fmt.Fprintln(w, "</g>")
+}
+
+// Returns the content of the image with the ids, and the references to them, given a
+// prefix unique to this drawing of the image.  This keeps the ids unique when the icon is
+// drawn more than once, or the SVG is inlined within a document with other images.
+func (si *SVGIcon) prefixedContent() []byte {
+	if len(si.ids) == 0 {
+		return si.content
+	}
+
+	prefix := fmt.Sprintf("icon-%08x-%d-", si.contentHash, atomic.AddUint64(&svgIconInstances, 1))
+	content := si.content
+	for _, pattern := range svgIDPatternMatches {
+		content = pattern.ReplaceAllFunc(content, func(match []byte) []byte {
+			groups := pattern.FindSubmatch(match)
+			if !si.ids[string(groups[2])] {
+				return match
+			}
+			return append(append(append([]byte(nil), groups[1]...), prefix...), groups[2]...)
+		})
+	}
+	return content
+}
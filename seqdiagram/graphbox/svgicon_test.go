@@ -0,0 +1,52 @@
+package graphbox
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+const testSVGIcon = `<?xml version="1.0"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" viewBox="0 0 24 24">
+  <defs><linearGradient id="shade"><stop offset="0" stop-color="red"/></linearGradient></defs>
+  <script>alert("icon")</script>
+  <circle id="dot" cx="12" cy="12" r="10" fill="url(#shade)" data-id="other"/>
+  <use xlink:href="#dot"/>
+  <use href="#elsewhere"/>
+</svg>`
+
+func TestSVGIconRemovesScripts(t *testing.T) {
+	assert := assert.Assert(t)
+
+	icon, err := NewSVGIcon(strings.NewReader(testSVGIcon))
+
+	assert.Equal(err, nil)
+	assert.Equal(strings.Contains(string(icon.content), "script"), false)
+	assert.Equal(strings.Contains(string(icon.content), "alert"), false)
+	assert.Equal(strings.Contains(string(icon.content), "<circle"), true)
+	assert.Equal(icon.ids, map[string]bool{"shade": true, "dot": true})
+}
+
+func TestSVGIconPrefixesIDs(t *testing.T) {
+	assert := assert.Assert(t)
+
+	icon, err := NewSVGIcon(strings.NewReader(testSVGIcon))
+	assert.Equal(err, nil)
+
+	first, second := string(icon.prefixedContent()), string(icon.prefixedContent())
+
+	prefix := regexp.MustCompile(`id="(icon-[0-9a-f]{8}-\d+-)shade"`).FindStringSubmatch(first)
+	assert.Equal(len(prefix), 2)
+	assert.Equal(strings.Contains(first, `fill="url(#`+prefix[1]+`shade)"`), true)
+	assert.Equal(strings.Contains(first, `id="`+prefix[1]+`dot"`), true)
+	assert.Equal(strings.Contains(first, `xlink:href="#`+prefix[1]+`dot"`), true)
+
+	// Only ids declared by the image are prefixed
+	assert.Equal(strings.Contains(first, `data-id="other"`), true)
+	assert.Equal(strings.Contains(first, `href="#elsewhere"`), true)
+
+	// Each drawing of the icon has different ids
+	assert.Equal(strings.Contains(second, prefix[1]), false)
+}
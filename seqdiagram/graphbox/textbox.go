@@ -3,8 +3,6 @@ package graphbox
 import (
 	"fmt"
 	"strings"
-
-	"github.com/ajstarks/svgo"
 )
 
 const (
@@ -76,11 +74,11 @@ func (tb *TextBox) BoundingRect() Rect {
 }
 
 // Renders the text from the given point and gravity
-func (tb *TextBox) Render(svg *svg.SVG, x, y int, gravity Gravity) {
+func (tb *TextBox) Render(ctx DrawContext, x, y int, gravity Gravity) {
 	rect := tb.BoundingRect().PositionAt(x, y, gravity)
 	left := rect.X
 	currY := rect.Y
-	style := tb.textStyle()
+	style := ctx.Style(tb.textStyle(ctx))
 
 	for _, line := range tb.Lines {
 		var textLeft int
@@ -96,10 +94,11 @@ func (tb *TextBox) Render(svg *svg.SVG, x, y int, gravity Gravity) {
 			textLeft = left + rect.W - lineW
 		}
 
-		textBottom := currY + lineH - (tb.FontSize*1/4 - 1)
+		textBottom := currY + tb.Font.Ascent(line, float64(tb.FontSize))
 
 		if line != "" {
-			svg.Text(textLeft, textBottom, line, style)
+			ctx.Graphic.useFont(tb.Font, line)
+			ctx.Canvas.Text(textLeft, textBottom, line, style)
 		}
 
 		currY += lineH + LINE_GAP
@@ -107,12 +106,22 @@ func (tb *TextBox) Render(svg *svg.SVG, x, y int, gravity Gravity) {
 }
 
 // Returns the text styling
-func (tb *TextBox) textStyle() string {
+func (tb *TextBox) textStyle(ctx DrawContext) string {
 	s := SvgStyle{}
 
-	s.Set("font-family", tb.Font.SvgName())
+	s.Set("font-family", ctx.Graphic.fontFamily(tb.Font))
 	s.Set("font-size", fmt.Sprintf("%dpx", tb.FontSize))
 
+	// Bold and italic fonts are chosen from the font faces by weight and style
+	if embeddableFont, isEmbeddable := tb.Font.(EmbeddableFont); isEmbeddable {
+		if embeddableFont.Weight() != "normal" {
+			s.Set("font-weight", embeddableFont.Weight())
+		}
+		if embeddableFont.Style() != "normal" {
+			s.Set("font-style", embeddableFont.Style())
+		}
+	}
+
 	if tb.Color != "" {
 		s.Set("fill", tb.Color)
 	}
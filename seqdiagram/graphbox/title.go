@@ -4,6 +4,11 @@ type TitleStyle struct {
 	Font     Font
 	FontSize int
 	Padding  Point
+
+	// The colour of the text, and of the rectangle behind the text.  Blank colours
+	// are drawn black and white respectively.
+	Color         string
+	KnockoutColor string
 }
 
 // A title
@@ -16,6 +21,7 @@ type Title struct {
 
 func NewTitle(toCol int, text string, style TitleStyle) *Title {
 	textBox := NewTextBox(style.Font, style.FontSize, LeftTextAlign)
+	textBox.Color = style.Color
 	textBox.AddText(text)
 
 	brect := textBox.BoundingRect()
@@ -45,6 +51,7 @@ func (al *Title) Draw(ctx DrawContext, point Point) {
 func (al *Title) renderMessage(ctx DrawContext, tx, ty int) {
 	rect := al.textBoxRect.PositionAt(tx, ty, SouthWestGravity)
 
-	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, "fill:white;stroke:white;")
-	al.textBox.Render(ctx.Canvas, tx, ty, SouthWestGravity)
+	knockoutColor := colorOr(al.style.KnockoutColor, "white")
+	ctx.Canvas.Rect(rect.X, rect.Y, rect.W, rect.H, ctx.Style("fill:"+knockoutColor+";stroke:"+knockoutColor+";"))
+	al.textBox.Render(ctx, tx, ty, SouthWestGravity)
 }
@@ -8,3 +8,48 @@ func maxInt(x, y int) int {
 		return y
 	}
 }
+
+// Returns the minimum of two integer.
+func minInt(x, y int) int {
+	if x < y {
+		return x
+	} else {
+		return y
+	}
+}
+
+// Returns the absolute value of an integer.
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	} else {
+		return x
+	}
+}
+
+// Returns the colour, or the default colour if the colour is blank.
+func colorOr(color string, defaultColor string) string {
+	if color == "" {
+		return defaultColor
+	}
+	return color
+}
+
+// Returns the maximum of two floats.
+func maxFloat(x, y float64) float64 {
+	if x > y {
+		return x
+	} else {
+		return y
+	}
+}
+
+// Returns true if the strings contain a string.
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package seqdiagram
 
 import (
 	"errors"
+	"strings"
 
 	"github.com/lmika/goseq/seqdiagram/graphbox"
 )
@@ -18,6 +19,21 @@ var graphboxArrowStemMapping = map[ArrowStem]graphbox.ActivityArrowStem{
 	ThickArrowStem:  graphbox.ThickArrowStem,
 }
 
+// The class names of the arrow stems
+var arrowStemClassNames = map[ArrowStem]string{
+	SolidArrowStem:  "solid",
+	DashedArrowStem: "dashed",
+	ThickArrowStem:  "thick",
+}
+
+var graphboxNoteShapeMapping = map[NoteShape]graphbox.NoteBoxShape{
+	RectNoteShape:    graphbox.RectNoteShape,
+	FoldedNoteShape:  graphbox.FoldedNoteShape,
+	RoundedNoteShape: graphbox.RoundedNoteShape,
+	HexagonNoteShape: graphbox.HexagonNoteShape,
+	CloudNoteShape:   graphbox.CloudNoteShape,
+}
+
 // Load the internal font
 func mustLoadFont() *graphbox.TTFFont {
 	font, err := loadInternalFont(dejaVuSansFont)
@@ -44,10 +60,41 @@ type graphicBuilder struct {
 	Style   *DiagramStyles
 
 	actorInfos []actorInfo
+	actorIndex map[*Actor]int
+
+	// The rows of the time points, and the durations to place between them
+	timePointRows map[string]int
+	durations     []*Duration
+
+	// The rows of the actions, the calls to draw activations for, and the index of the
+	// items to draw the activations beneath
+	actionRows      map[*Action]int
+	activatedCalls  []*Action
+	activationIndex int
+
+	// The indices of the items of the actor boxes, which activations continuing from or
+	// onto other pages are drawn beneath
+	actorBoxIndex map[*Actor]int
 }
 
 func newGraphicBuilder(d *Diagram, style *DiagramStyles) (*graphicBuilder, error) {
-	return &graphicBuilder{d, nil, style, nil}, nil
+	style, err := style.withOverrides(d.StyleOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	style, err = style.withFallbackFonts()
+	if err != nil {
+		return nil, err
+	}
+
+	return &graphicBuilder{
+		Diagram:       d,
+		Style:         style.withItemColors(),
+		timePointRows: make(map[string]int),
+		actionRows:    make(map[*Action]int),
+		actorBoxIndex: make(map[*Actor]int),
+	}, nil
 }
 
 func (gb *graphicBuilder) buildGraphic() *graphbox.Graphic {
@@ -55,20 +102,24 @@ func (gb *graphicBuilder) buildGraphic() *graphbox.Graphic {
 	gb.Graphic = graphbox.NewGraphic(rows, cols)
 
 	gb.Graphic.Margin = gb.Style.Margin
+	gb.Graphic.Background = gb.Style.BackgroundColor
 	gb.Graphic.ShowGrid = false
 
 	gb.addActors()
+	gb.activationIndex = gb.Graphic.ItemCount()
 
 	if len(gb.Diagram.Items) == 0 {
 		gb.Graphic.Put(2, 0, &graphbox.Spacer{graphbox.Point{0, 64}})
 	} else {
 		row := 2
 		gb.putItemsInSlice(&row, 0, gb.Diagram.Items)
+		gb.putDurations()
+		gb.putActivations()
 	}
 
 	// Add a title
 	if gb.Diagram.Title != "" {
-		gb.Graphic.Put(0, 0, graphbox.NewTitle(cols, gb.Diagram.Title, gb.Style.Title))
+		gb.Graphic.PutWithClass(0, 0, graphbox.NewTitle(cols, gb.Diagram.Title, gb.Style.Title), "title")
 	}
 
 	return gb.Graphic
@@ -78,7 +129,21 @@ func (gb *graphicBuilder) buildGraphic() *graphbox.Graphic {
 func (gb *graphicBuilder) putItemsInSlice(row *int, depth int, items []SequenceItem) {
 	for _, item := range items {
 		switch itemDetails := item.(type) {
+		case *Duration:
+			// Durations are placed once the rows of the time points are known
+			gb.durations = append(gb.durations, itemDetails)
+			continue
+		case *PageBreak:
+			// Page breaks are only used to split the diagram into pages
+			continue
 		case *Action:
+			if itemDetails.TimePoint != "" {
+				gb.timePointRows[itemDetails.TimePoint] = *row
+			}
+			if itemDetails.Return != nil {
+				gb.activatedCalls = append(gb.activatedCalls, itemDetails)
+			}
+			gb.actionRows[itemDetails] = *row
 			gb.putAction(*row, itemDetails)
 		case *Note:
 			gb.putNote(*row, itemDetails)
@@ -113,6 +178,8 @@ func (gb *graphicBuilder) calcItemsInSlice(items []SequenceItem) int {
 				}
 			}
 			rows += 1
+		case *Duration, *PageBreak:
+			// Durations and page breaks do not occupy a row
 		default:
 			rows++
 		}
@@ -122,69 +189,73 @@ func (gb *graphicBuilder) calcItemsInSlice(items []SequenceItem) int {
 
 // Places a note
 func (gb *graphicBuilder) putNote(row int, note *Note) {
-	if (note.Actor2 == nil) || (note.Actor1 == note.Actor2) {
+	if note.Align == AcrossNoteAlignment {
+		gb.putAcrossNote(row, note)
+	} else if (note.Actor2 == nil) || (note.Actor1 == note.Actor2) {
 		gb.putSingleActorNote(row, note.Actor1, note)
 	} else {
-		var leftActor, rightActor *Actor
-		if gb.colOfActor(note.Actor1) < gb.colOfActor(note.Actor2) {
-			leftActor, rightActor = note.Actor1, note.Actor2
-		} else {
-			leftActor, rightActor = note.Actor2, note.Actor1
-		}
-
-		switch note.Align {
-		case OverNoteAlignment:
-			gb.putMultiActorOverNote(row, leftActor, rightActor, note)
-		case LeftNoteAlignment:
-			gb.putSingleActorNote(row, leftActor, note)
-		case RightNoteAlignment:
-			gb.putSingleActorNote(row, rightActor, note)
+		fromCol, toCol := gb.colOfActor(note.Actor1), gb.colOfActor(note.Actor2)
+		if toCol < fromCol {
+			fromCol, toCol = toCol, fromCol
 		}
+		gb.putMultiActorNote(row, fromCol, toCol, note)
 	}
 }
 
-// Places a note over a single actor
-func (gb *graphicBuilder) putSingleActorNote(row int, actor *Actor, note *Note) {
-	var pos graphbox.NoteBoxPos
+// Returns the note box position of a note alignment
+func noteBoxPos(align NoteAlignment) graphbox.NoteBoxPos {
+	switch align {
+	case LeftNoteAlignment:
+		return graphbox.LeftNotePos
+	case RightNoteAlignment:
+		return graphbox.RightNotePos
+	default:
+		return graphbox.CenterNotePos
+	}
+}
 
-	if note.Align == LeftNoteAlignment {
-		pos = graphbox.LeftNotePos
-	} else if note.Align == OverNoteAlignment {
-		pos = graphbox.CenterNotePos
-	} else if note.Align == RightNoteAlignment {
-		pos = graphbox.RightNotePos
+// Returns the note box style of a note
+func (gb *graphicBuilder) noteBoxStyle(note *Note) graphbox.NoteBoxStyle {
+	style := gb.Style.NoteBox
+	style.Shape = graphboxNoteShapeMapping[note.Shape]
+	if note.Color != "" {
+		style.Color = note.Color
+	}
+	if note.FillColor != "" {
+		style.FillColor = note.FillColor
+	}
+	if note.TextColor != "" {
+		style.TextColor = note.TextColor
 	}
+	return style
+}
 
+// Places a note over a single actor
+func (gb *graphicBuilder) putSingleActorNote(row int, actor *Actor, note *Note) {
 	col := gb.colOfActor(actor)
-	gb.Graphic.Put(row, col, graphbox.NewNoteBox(note.Message, gb.Style.NoteBox, pos))
+	gb.Graphic.PutWithClass(row, col, graphbox.NewNoteBox(note.Message, gb.noteBoxStyle(note), noteBoxPos(note.Align)), "note")
 }
 
-// Places a note over a multiple actors.  This actually uses the divider graphics object
-// with the style adopted from the note style
-func (gb *graphicBuilder) putMultiActorOverNote(row int, leftActor *Actor, rightActor *Actor, note *Note) {
-	dividerBox := graphbox.DividerStyle{
-		Font:        gb.Style.NoteBox.Font,
-		FontSize:    gb.Style.NoteBox.FontSize,
-		Padding:     gb.Style.NoteBox.Padding,
-		Margin:      gb.Style.NoteBox.Margin,
-		TextPadding: graphbox.Point{0, 0},
-		Shape:       graphbox.DSFramedRect,
-		Overlap:     gb.Style.MultiNoteOverlap,
-	}
-
-	fromCol := gb.colOfActor(leftActor)
-	toCol := gb.colOfActor(rightActor)
+// Places a note spanning the lifelines of multiple actors
+func (gb *graphicBuilder) putMultiActorNote(row int, fromCol int, toCol int, note *Note) {
+	noteBox := graphbox.NewSpanNoteBox(toCol, gb.Style.MultiNoteOverlap, note.Message, gb.noteBoxStyle(note),
+		noteBoxPos(note.Align))
+	gb.Graphic.PutWithClass(row, fromCol, noteBox, "note")
+}
 
-	// TODO: This was just to avoid bad styling of notes which reference 'left' and 'right'
-	// This needs to be fixed using proper styling, instead of this hack.
-	if fromCol == 0 {
-		fromCol = 1
-	}
-	if toCol == gb.Graphic.Cols()-1 {
-		toCol = gb.Graphic.Cols() - 2
+// Places a note spanning the lifelines of all actors.  If there are no actors, the note
+// spans the entire diagram.
+func (gb *graphicBuilder) putAcrossNote(row int, note *Note) {
+	fromCol, toCol := 0, gb.Graphic.Cols()-1
+	if actors := gb.Diagram.Actors; len(actors) > 0 {
+		fromCol, toCol = gb.colOfActor(actors[0]), gb.colOfActor(actors[len(actors)-1])
 	}
 
-	gb.Graphic.Put(row, fromCol, graphbox.NewDivider(toCol, note.Message, dividerBox))
+	if fromCol == toCol {
+		gb.putSingleActorNote(row, gb.Diagram.Actors[0], note)
+	} else {
+		gb.putMultiActorNote(row, fromCol, toCol, note)
+	}
 }
 
 // Places an action
@@ -197,7 +268,145 @@ func (gb *graphicBuilder) putAction(row int, action *Action) {
 	style.ArrowHead = gb.Style.ArrowHeads[action.Arrow.Head] //graphboxArrowHeadMapping[action.Arrow.Head]
 	style.ArrowStem = graphboxArrowStemMapping[action.Arrow.Stem]
 
-	gb.Graphic.Put(row, fromCol, graphbox.NewActivityLine(toCol, fromCol == toCol, action.Message, style))
+	class := "message " + arrowStemClassNames[action.Arrow.Stem]
+	if fromCol == toCol {
+		class += " self"
+	}
+
+	gb.Graphic.PutWithClass(row, fromCol, graphbox.NewActivityLine(toCol, fromCol == toCol, action.Message, style), class)
+
+	if action.Note != nil {
+		gb.putMessageNote(row, fromCol, toCol, action.Note)
+	}
+}
+
+// Places a note beside the end of an action, on the same row as the action
+func (gb *graphicBuilder) putMessageNote(row int, fromCol int, toCol int, note *Note) {
+	offsetX := 0
+	if fromCol == toCol {
+		offsetX = gb.Style.ActivityLine.SelfRefWidth
+	}
+
+	col := maxInt(fromCol, toCol)
+	gb.Graphic.PutWithClass(row, col, graphbox.NewMessageNoteBox(offsetX, note.Message, gb.noteBoxStyle(note)), "note message-note")
+}
+
+// Places the activations of the calls on the lifelines of the called actors.  The
+// activations are drawn beneath the other items, with nested activations of an actor
+// drawn to the right of the activations they are nested within.  Calls which are not
+// returned within the diagram have no activation, unless the diagram is a page, where
+// the activations of calls continuing from or onto other pages are drawn to the top or
+// bottom of the page.
+func (gb *graphicBuilder) putActivations() {
+	type activationRows struct {
+		col, fromRow, toRow int
+	}
+	placed := make([]activationRows, 0, len(gb.activatedCalls))
+
+	calls := gb.activatedCalls
+	if gb.Diagram.continuations != nil {
+		calls = append(append([]*Action(nil), gb.Diagram.continuations.calls...), calls...)
+	}
+
+	style := gb.Style.Activation
+	for _, call := range calls {
+		fromRow, hasFrom := gb.actionRows[call]
+		toRow, hasTo := gb.actionRows[call.Return]
+		if gb.Diagram.continuations != nil {
+			if !hasFrom {
+				fromRow, hasFrom = posObjectY, true
+			}
+			if !hasTo {
+				toRow, hasTo = gb.Graphic.Rows()-1, true
+			}
+		}
+		if !hasFrom || !hasTo || (toRow < fromRow) {
+			continue
+		}
+
+		col := gb.colOfActor(call.To)
+		depth := 0
+		for _, other := range placed {
+			if (other.col == col) && (other.fromRow <= fromRow) && (other.toRow >= toRow) {
+				depth++
+			}
+		}
+
+		offset := graphbox.Point{depth * style.Width / 2, 0}
+		if (call.From == call.To) && (fromRow != posObjectY) {
+			offset.Y = gb.Style.ActivityLine.SelfRefHeight
+		}
+
+		activation := graphbox.NewActivation(toRow, offset, style)
+		if (fromRow == posObjectY) || (toRow == gb.Graphic.Rows()-1) {
+			gb.insertBeneathActorBoxes(call.To, fromRow, col, activation)
+		} else {
+			gb.Graphic.InsertWithClass(gb.activationIndex, fromRow, col, activation, "activation")
+			gb.activationIndex++
+		}
+		placed = append(placed, activationRows{col, fromRow, toRow})
+	}
+}
+
+// Inserts an activation beneath the boxes of an actor, so that the ends of activations
+// continuing from or onto other pages are hidden by the boxes
+func (gb *graphicBuilder) insertBeneathActorBoxes(actor *Actor, row int, col int, activation *graphbox.Activation) {
+	index := gb.actorBoxIndex[actor]
+	gb.Graphic.InsertWithClass(index, row, col, activation, "activation")
+
+	for other, otherIndex := range gb.actorBoxIndex {
+		if otherIndex >= index {
+			gb.actorBoxIndex[other]++
+		}
+	}
+	gb.activationIndex++
+}
+
+// Places the durations in the left margin.  Each duration is given a separate lane, in
+// the order the durations are declared.  Durations with time points not within the
+// diagram are skipped, unless the diagram is a page, where the durations of the view
+// which span the page are drawn to the top or bottom of the page.
+func (gb *graphicBuilder) putDurations() {
+	durations := gb.durations
+	if gb.Diagram.continuations != nil {
+		durations = gb.Diagram.continuations.durations
+	}
+
+	offsetX := 0
+	for _, duration := range durations {
+		fromRow, hasFrom := gb.timePointRow(duration.From)
+		toRow, hasTo := gb.timePointRow(duration.To)
+		if !hasFrom || !hasTo || ((fromRow == toRow) && !gb.hasTimePoint(duration.From)) {
+			continue
+		} else if toRow < fromRow {
+			fromRow, toRow = toRow, fromRow
+		}
+
+		durationLine := graphbox.NewDurationLine(toRow, offsetX, duration.Message, gb.Style.Duration)
+		gb.Graphic.PutWithClass(fromRow, 0, durationLine, "duration")
+		offsetX += durationLine.Width()
+	}
+}
+
+// Returns the row of a time point.  On a page, the time points before or after the page
+// are at the top or bottom of the page.
+func (gb *graphicBuilder) timePointRow(name string) (int, bool) {
+	if row, hasRow := gb.timePointRows[name]; hasRow {
+		return row, true
+	} else if continuations := gb.Diagram.continuations; continuations != nil {
+		if continuations.timePointsBefore[name] {
+			return posObjectY, true
+		} else if continuations.timePointsAfter[name] {
+			return gb.Graphic.Rows() - 1, true
+		}
+	}
+	return 0, false
+}
+
+// Returns true if a time point is marked within the diagram
+func (gb *graphicBuilder) hasTimePoint(name string) bool {
+	_, hasRow := gb.timePointRows[name]
+	return hasRow
 }
 
 // Places a divider
@@ -206,7 +415,14 @@ func (gb *graphicBuilder) putDivider(row int, action *Divider) {
 	toCol := gb.Graphic.Cols() - 1
 	style := gb.Style.Divider[action.Type]
 
-	gb.Graphic.Put(row, fromCol, graphbox.NewDivider(toCol, action.Message, style))
+	class := "divider"
+	for name, dividerType := range dividerStyleNames {
+		if dividerType == action.Type {
+			class += " divider-" + name
+		}
+	}
+
+	gb.Graphic.PutWithClass(row, fromCol, graphbox.NewDivider(toCol, action.Message, style), class)
 }
 
 // Places a block
@@ -266,13 +482,16 @@ func (gb *graphicBuilder) putBlockSegmentsSequentially(row *int, depth int, acti
 			segPrefix = "loop"
 		}
 
+		// The class is named after the type of block, even if the prefix is changed
+		class := "block block-" + segPrefix
+
 		if seg.Prefix != "" {
 			segPrefix = seg.Prefix
 		}
 
 		block := graphbox.NewBlock(endRow, endCol, nestDepth, i == len(action.Segments)-1,
 			segPrefix, showPrefix, seg.Message, style)
-		gb.Graphic.Put(startRow, startCol, block)
+		gb.Graphic.PutWithClass(startRow, startCol, block, class)
 
 		startRow = endRow
 	}
@@ -293,25 +512,25 @@ func (gb *graphicBuilder) calcRowsAndCols() (int, int) {
 // Determine actor information.  Returns the number of colums required
 func (gb *graphicBuilder) determineActorInfo() int {
 	gb.actorInfos = make([]actorInfo, len(gb.Diagram.Actors))
+	gb.actorIndex = make(map[*Actor]int)
 
 	// Allocate the columns
 	cols := posObjectLeftX
-	for _, actor := range gb.Diagram.Actors {
+	for i, actor := range gb.Diagram.Actors {
 		colsRequiredByActor := 1
 		actorCol := cols
 
-		if actor.rank != -1 {
-			if gb.actorInfos[actor.rank].ExtraLeftCol {
-				colsRequiredByActor++
-				actorCol++
-			}
-			if gb.actorInfos[actor.rank].ExtraRightCol {
-				colsRequiredByActor++
-			}
-
-			gb.actorInfos[actor.rank].Col = actorCol
-			cols += colsRequiredByActor
+		if gb.actorInfos[i].ExtraLeftCol {
+			colsRequiredByActor++
+			actorCol++
+		}
+		if gb.actorInfos[i].ExtraRightCol {
+			colsRequiredByActor++
 		}
+
+		gb.actorInfos[i].Col = actorCol
+		gb.actorIndex[actor] = i
+		cols += colsRequiredByActor
 	}
 
 	return cols
@@ -333,46 +552,79 @@ func (gb *graphicBuilder) addActors() {
 		}
 
 		col := gb.colOfActor(actor)
+		class := "actor actor-" + cssClassName(actor.Name)
 
+		// Activations continuing from or onto other pages are drawn after the lifeline,
+		// beneath the actor boxes
+		gb.actorBoxIndex[actor] = gb.Graphic.ItemCount()
 		if actor.Lifeline {
-			gb.Graphic.Put(posObjectY, col, &graphbox.LifeLine{
+			gb.Graphic.PutWithClass(posObjectY, col, &graphbox.LifeLine{
 				TR: bottomRow,
 				TC: col,
 				Style: graphbox.LifeLineStyle{
-					Color: actor.Color,
+					Color: colorOr(actor.Color, gb.Style.ForegroundColor),
 				},
-			})
+			}, "lifeline lifeline-"+cssClassName(actor.Name))
+			gb.actorBoxIndex[actor]++
 		}
 
-		if actor.Icon != nil {
-			actorIconStyle := gb.Style.ActorIconBox
-			actorIconStyle.Color = actor.Color
-			actorIconStyle.TextColor = actor.TextColor
-
-			if actor.InHeader {
-				gb.Graphic.Put(posObjectY, col, graphbox.NewActorIconBox(actor.Label, actor.Icon.graphboxIcon(), actorIconStyle, actorBoxPos|graphbox.TopActorBox))
+		newActorBox := gb.actorBoxFactory(actor)
+		if actor.InHeader {
+			gb.Graphic.PutWithClass(posObjectY, col, newActorBox(actorBoxPos|graphbox.TopActorBox), class)
+			if actor.InFooter {
+				gb.Graphic.PutWithClass(bottomRow, col, newActorBox(actorBoxPos|graphbox.BottomActorBox), class)
 			}
 		} else {
-			// Configure the style
-			actorStyle := gb.Style.ActorBox
-			actorStyle.Color = actor.Color
-			actorStyle.TextColor = actor.TextColor
-
-			if actor.InHeader {
-				gb.Graphic.Put(posObjectY, col, graphbox.NewActorBox(actor.Label, actorStyle, actorBoxPos|graphbox.TopActorBox))
-				if actor.InFooter {
-					gb.Graphic.Put(bottomRow, col, graphbox.NewActorBox(actor.Label, actorStyle, actorBoxPos|graphbox.BottomActorBox))
-				}
-			} else {
-				if actor.InFooter {
-					// Use the TopActorBox as that performs the layout
-					gb.Graphic.Put(bottomRow, col, graphbox.NewActorBox(actor.Label, actorStyle, actorBoxPos|graphbox.TopActorBox))
-				}
+			if actor.InFooter {
+				// Use the TopActorBox as that performs the layout
+				gb.Graphic.PutWithClass(bottomRow, col, newActorBox(actorBoxPos|graphbox.TopActorBox), class)
 			}
 		}
 	}
 }
 
+// Returns a function which creates the header or footer of an actor at a position.
+// Actors with icons use an icon box, otherwise an actor box.
+func (gb *graphicBuilder) actorBoxFactory(actor *Actor) func(pos graphbox.ActorBoxPos) graphbox.GraphboxItem {
+	if actor.Icon != nil {
+		actorIconStyle := gb.Style.ActorIconBox
+		actorIconStyle.Color = colorOr(actor.Color, actorIconStyle.Color)
+		actorIconStyle.TextColor = colorOr(actor.TextColor, actorIconStyle.TextColor)
+
+		return func(pos graphbox.ActorBoxPos) graphbox.GraphboxItem {
+			return graphbox.NewActorIconBox(actorLabel(actor), actor.Icon.graphboxIcon(), actorIconStyle, pos)
+		}
+	}
+
+	// Configure the style
+	actorStyle := gb.Style.ActorBox
+	actorStyle.Color = colorOr(actor.Color, actorStyle.Color)
+	actorStyle.TextColor = colorOr(actor.TextColor, actorStyle.TextColor)
+
+	return func(pos graphbox.ActorBoxPos) graphbox.GraphboxItem {
+		return graphbox.NewActorBox(actorLabel(actor), actorStyle, pos)
+	}
+}
+
+// Returns the label of an actor, preceded by the stereotype if the actor has one
+func actorLabel(actor *Actor) string {
+	if actor.Stereotype != "" {
+		return "\u00ab" + actor.Stereotype + "\u00bb\n" + actor.Label
+	}
+	return actor.Label
+}
+
+// Returns a name which can be used within a CSS class name.  Characters which are not
+// letters, digits, hyphens or underscores are replaced with hyphens.
+func cssClassName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
 // Returns the column position of an actor
 func (gb *graphicBuilder) colOfActor(actor *Actor) int {
 	if actor == LeftOffsideActor {
@@ -380,6 +632,6 @@ func (gb *graphicBuilder) colOfActor(actor *Actor) int {
 	} else if actor == RightOffsideActor {
 		return gb.Graphic.Cols() - 1
 	} else {
-		return gb.actorInfos[actor.rank].Col
+		return gb.actorInfos[gb.actorIndex[actor]].Col
 	}
 }
@@ -0,0 +1,132 @@
+package seqdiagram
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Renders a diagram, returning the SVG
+func renderSVG(t *testing.T, diagram *Diagram, options *ImageOptions) string {
+	if options.Style == nil {
+		options.Style = DefaultStyle
+	}
+
+	out := new(bytes.Buffer)
+	if err := diagram.WriteSVGWithOptions(out, options); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// Returns the number of elements of the SVG of a diagram with the class
+func countClass(t *testing.T, diagram *Diagram, options *ImageOptions, class string) int {
+	return strings.Count(renderSVG(t, diagram, options), `class="`+class+`"`)
+}
+
+func TestActivationsOfCalls(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.call() {\n B->C.x()\n return ok\n}\nA->D: other\n")
+
+	assert.Equal(countClass(t, diagram, &ImageOptions{}, "activation"), 1)
+}
+
+func TestActivationsOfCallsWithHiddenActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.call() {\n B->C.x()\n return ok\n}\nA->D: other\n")
+
+	assert.Equal(countClass(t, diagram, &ImageOptions{HiddenActors: map[string]string{"D": ""}}, "activation"), 1)
+	assert.Equal(countClass(t, diagram, &ImageOptions{HiddenActors: map[string]string{"B": ""}}, "activation"), 0)
+}
+
+func TestActivationsOfCallsWithCollapsedActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant Backend (members=\"B, C\")\nA->B.call() {\n B->C.x()\n return ok\n}\nA->D: other\n")
+
+	assert.Equal(countClass(t, diagram, &ImageOptions{Collapse: []string{"Backend"}}, "activation"), 1)
+}
+
+func TestDurationLinesBetweenTimePoints(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "Client->Server (at=\"t1\"): Request\nServer->DB (at=\"q1\"): Query\n"+
+		"DB->Server (at=\"q2\"): Result\nServer->Client (at=\"t2\"): Response\n"+
+		"duration t1..t2: < 200ms\nduration q1..q2: < 50ms\n")
+
+	assert.Equal(countClass(t, diagram, &ImageOptions{}, "duration"), 2)
+
+	// The time points of the messages of hidden actors are not drawn
+	assert.Equal(countClass(t, diagram, &ImageOptions{HiddenActors: map[string]string{"DB": ""}}, "duration"), 1)
+}
+
+func TestNotesAcrossParticipants(t *testing.T) {
+	assert := assert.Assert(t)
+
+	for _, src := range []string{
+		"note across: No participants\n",
+		"A->A: Self\nnote across: One participant\n",
+		"A->B: Hi\nB->C: Hi\nnote across: Several participants\n",
+	} {
+		assert.Equal(countClass(t, mustParseDiagram(t, src), &ImageOptions{}, "note"), 1)
+	}
+}
+
+func TestMessageNotesDrawnWithMessages(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Create [note: idempotent]\nB->C: Insert\nnote on message: In a transaction\n")
+
+	assert.Equal(countClass(t, diagram, &ImageOptions{}, "note message-note"), 2)
+	assert.Equal(countClass(t, diagram, &ImageOptions{HiddenActors: map[string]string{"C": ""}}, "note message-note"), 1)
+}
+
+func TestHeadersAndFootersOfParticipantsWithIcons(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant User (icon=\"human\")\n"+
+		"participant Server (icon=\"component\", header=\"none\")\nparticipant Queue (icon=\"queue\", footer=\"none\")\n"+
+		"participant Hidden (icon=\"cloud\", header=\"none\", footer=\"none\")\n"+
+		"User->Server: Request\nServer->Queue: Publish\nServer->Hidden: Lookup\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{})
+	for name, count := range map[string]int{"User": 2, "Server": 1, "Queue": 1, "Hidden": 0} {
+		assert.Equal(strings.Count(svg, `class="actor actor-`+name+`"`), count)
+	}
+}
+
+func TestClassesOfDiagramParts(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "title: Classes\n"+
+		"Client->Web: Request\nnote right of Web: Checked\nalt: Valid\n  Web-->Client: Response\nend\n"+
+		"Web->Web: Log\nhorizontal line: Done\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{})
+	for _, class := range []string{"title", "background", "actor actor-Client", "actor actor-Web",
+		"lifeline lifeline-Web", "message solid", "message dashed", "message solid self", "note",
+		"block block-alt", "divider divider-line"} {
+
+		assert.Equal(strings.Contains(svg, `class="`+class+`"`), true)
+	}
+
+	assert.Equal(cssClassName("Web Server!"), "Web-Server-")
+}
+
+func TestStyleClassesOption(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Hi\nnote over B: Note\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{StyleClasses: true})
+	assert.Equal(strings.Contains(svg, ` style="`), false)
+	assert.Equal(strings.Contains(svg, `class="goseq-`), true)
+
+	svg = renderSVG(t, diagram, &ImageOptions{})
+	assert.Equal(strings.Contains(svg, ` style="`), true)
+	assert.Equal(strings.Contains(svg, `class="goseq-`), false)
+}
@@ -0,0 +1,72 @@
+package seqdiagram
+
+import (
+	"fmt"
+	"html"
+	"io"
+)
+
+// The number of rows occupied by the title and the actor headers
+const headerRows = 2
+
+// The style of the HTML page.  The header stays at the top of the page as the
+// rest of the diagram is scrolled, so it is given a background colour.
+const htmlPageStyle = `body { margin: 0; }
+.goseq-header { position: sticky; top: 0; background: %s; }
+.goseq-header svg, .goseq-body svg { display: block; }`
+
+// Write the diagram as an HTML page for viewing on screen.  The diagram is split into two
+// SVGs: the title and actor headers, which stay at the top of the page while scrolling,
+// and the rest of the diagram.  The font faces and style classes of both are written once,
+// to the style sheet of the page.  Page breaks and the embedded option are ignored.
+func (d *Diagram) WriteHTMLWithOptions(w io.Writer, options *ImageOptions) error {
+	view, err := d.viewFor(options)
+	if err != nil {
+		return err
+	}
+
+	gb, err := newGraphicBuilder(view, options.Style)
+	if err != nil {
+		return err
+	}
+
+	graphics := gb.buildGraphic()
+	graphics.Scale = options.Scale
+	graphics.StyleClasses = options.StyleClasses
+	graphics.FontURL = options.FontURL
+	graphics.OmitStyleSheet = true
+
+	// The header needs a background to hide the diagram scrolling under it, even
+	// if the diagram itself is transparent
+	headerBackground := colorOr(colorOr(graphics.Background, gb.Style.KnockoutColor), "white")
+	if options.Transparent {
+		graphics.Background = ""
+	}
+
+	_, height := graphics.Size()
+	headerHeight := graphics.HeightAboveRow(headerRows)
+
+	fmt.Fprintln(w, "<!DOCTYPE html>")
+	fmt.Fprintln(w, "<html>")
+	fmt.Fprintln(w, "<head>")
+	fmt.Fprintln(w, `<meta charset="utf-8">`)
+	fmt.Fprintf(w, "<title>%s</title>\n", html.EscapeString(d.Title))
+	fmt.Fprintf(w, "<style>\n"+htmlPageStyle+"\n", headerBackground)
+	graphics.WriteStyleSheet(w)
+	fmt.Fprintln(w, "</style>")
+	fmt.Fprintln(w, "</head>")
+	fmt.Fprintln(w, "<body>")
+
+	fmt.Fprintln(w, `<div class="goseq-header">`)
+	graphics.DrawSVGSlice(w, 0, headerHeight)
+	fmt.Fprintln(w, "</div>")
+
+	fmt.Fprintln(w, `<div class="goseq-body">`)
+	graphics.DrawSVGSlice(w, headerHeight, height)
+	fmt.Fprintln(w, "</div>")
+
+	fmt.Fprintln(w, "</body>")
+	fmt.Fprintln(w, "</html>")
+
+	return nil
+}
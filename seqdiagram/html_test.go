@@ -0,0 +1,25 @@
+package seqdiagram
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+func TestHTMLWritesStyleSheetOnce(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "title: Flow\nA->B: Request\nB->A: Response\n")
+
+	out := new(bytes.Buffer)
+	err := diagram.WriteHTMLWithOptions(out, &ImageOptions{Style: DefaultStyle, StyleClasses: true})
+	assert.Equal(err, nil)
+
+	page := out.String()
+	head := page[:strings.Index(page, "</head>")]
+	assert.Equal(strings.Count(page, "<style>"), 1)
+	assert.Equal(strings.Count(page, "<svg"), 2)
+	assert.Equal(strings.Contains(head, ".goseq-"), true)
+}
@@ -5,6 +5,8 @@ package seqdiagram
 
 import (
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/lmika/goseq/seqdiagram/parse"
 )
@@ -15,6 +17,15 @@ type Diagram struct {
 	Title                  string
 	Actors                 []*Actor
 	Items                  []SequenceItem
+	Views                  map[string]*View
+
+	// Style attributes declared in the diagram which override the styles of the image,
+	// keyed by the style identifier (e.g. "arrow") and then the attribute name.
+	StyleOverrides map[string]map[string]string
+
+	// The calls and durations which continue onto the diagram from other pages, if the
+	// diagram is a page of a view.  Nil for all other diagrams.
+	continuations *pageContinuations
 }
 
 // Creates a new, empty diagram
@@ -54,19 +65,34 @@ func (d *Diagram) GetOrAddActorWithOptions(name string, label string) *Actor {
 	}
 
 	na := &Actor{
-		Name:      name,
-		Label:     label,
-		InHeader:  true,
-		InFooter:  true,
-		Lifeline:  true,
-		Color:     "black",
-		TextColor: "black",
-		rank:      len(d.Actors),
+		Name:     name,
+		Label:    label,
+		InHeader: true,
+		InFooter: true,
+		Lifeline: true,
+		rank:     len(d.Actors),
 	}
 	d.Actors = append(d.Actors, na)
 	return na
 }
 
+// Sorts the actors by their explicit order.  Actors without an explicit order are
+// placed after the ordered actors in the order in which they were first mentioned.
+func (d *Diagram) SortActors() {
+	sort.SliceStable(d.Actors, func(i, j int) bool {
+		ai, aj := d.Actors[i], d.Actors[j]
+		if ai.Order != aj.Order {
+			if ai.Order == 0 {
+				return false
+			} else if aj.Order == 0 {
+				return true
+			}
+			return ai.Order < aj.Order
+		}
+		return ai.rank < aj.rank
+	})
+}
+
 // Adds a new sequence item
 func (d *Diagram) AddSequenceItem(item SequenceItem) {
 	d.Items = append(d.Items, item)
@@ -77,19 +103,14 @@ func (d *Diagram) WriteSVG(w io.Writer) error {
 	return d.WriteSVGWithOptions(w, DefaultOptions)
 }
 
-// Write the diagram as an SVG using a specific style
+// Write the diagram as an SVG using a specific style.  Page breaks are ignored.
 func (d *Diagram) WriteSVGWithOptions(w io.Writer, options *ImageOptions) error {
-	gb, err := newGraphicBuilder(d, options.Style)
+	view, err := d.viewFor(options)
 	if err != nil {
 		return err
 	}
 
-	// Generate the SVG file
-	graphics := gb.buildGraphic()
-	graphics.Viewport = options.Embedded
-	graphics.DrawSVG(w)
-
-	return nil
+	return view.writeSVG(w, options)
 }
 
 // Options for SVG image generation
@@ -100,12 +121,92 @@ type ImageOptions struct {
 	// If true, generate attributes to make the SVG suitable for embedding
 	// in other documents (e.g. HTML).
 	Embedded bool
+
+	// If true, reorder the actors without an explicit order to reduce the
+	// distance travelled by the actions.
+	AutoOrder bool
+
+	// The enabled features.  Used to select which conditional sections
+	// of the diagram will be rendered.
+	Features []string
+
+	// The name of the view declared in the diagram to render.  If blank, the
+	// entire diagram is rendered.
+	View string
+
+	// Actors to hide, mapped to the name of the actor their actions are to
+	// be rerouted through.  If the name is blank, the actions are removed.
+	HiddenActors map[string]string
+
+	// The names of the composite actors to collapse
+	Collapse []string
+
+	// The labels marking the range of items to render.  If the from label is blank,
+	// the range starts at the beginning of the diagram.  If the to label is blank,
+	// the range finishes at the end of the diagram.
+	FromLabel string
+	ToLabel   string
+
+	// The maximum height of a page.  If greater than 0, the diagram is broken into
+	// additional pages wherever a page would be taller than this height.  Only
+	// used when writing the diagram as pages.
+	PageHeight int
+
+	// If true, the background is left transparent instead of being filled with the
+	// background colour of the style.
+	Transparent bool
+
+	// If true, the styles of the SVG elements are written to a style sheet instead of
+	// being set on each element, so that the diagram can be restyled with CSS when it is
+	// embedded within an HTML page.  The elements are always grouped with classes naming
+	// the parts of the diagram, such as "actor", "message" and "note".
+	StyleClasses bool
+
+	// The URL of the fonts used by the SVG.  If blank, the fonts are embedded within the
+	// SVG with only the glyphs used by the diagram.  If the URL ends with a slash, the name
+	// of the font and ".ttf" are appended to it.  If "none", the fonts are not declared and
+	// are left to the viewer.
+	FontURL string
+
+	// The scale factor of the image.  A value of 0 or 1 will produce an image
+	// at its natural size.
+	Scale float64
 }
 
 // The default options
 var DefaultOptions = &ImageOptions{
 	Style:    DefaultStyle,
 	Embedded: false,
+	Scale:    1,
+}
+
+// A named view of the diagram, as declared in the diagram itself
+type View struct {
+	Name string
+
+	// Actors to hide, mapped to the name of the actor their actions are to
+	// be rerouted through.  If the name is blank, the actions are removed.
+	HiddenActors map[string]string
+
+	// The names of the composite actors to collapse
+	Collapse []string
+}
+
+// Parses a list of actors to hide.  The list consists of comma separated actor names.
+// The actions of an actor can be rerouted through another actor using "Name=Proxy".
+func ParseHiddenActors(spec string) map[string]string {
+	hiddenActors := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		name, proxy := strings.TrimSpace(parts[0]), ""
+		if len(parts) > 1 {
+			proxy = strings.TrimSpace(parts[1])
+		}
+		if name != "" {
+			hiddenActors[name] = proxy
+		}
+	}
+	return hiddenActors
 }
 
 // A processing instruction
@@ -119,13 +220,30 @@ type Actor struct {
 	Name  string
 	Label string
 
-	Icon      ActorIcon
-	InHeader  bool
-	InFooter  bool
-	Lifeline  bool
+	Icon     ActorIcon
+	InHeader bool
+	InFooter bool
+	Lifeline bool
+
+	// The colours of the actor.  Blank colours use the foreground colour of the style.
 	Color     string
 	TextColor string
 
+	// The stereotype shown above the label.  Can be blank.
+	Stereotype string
+
+	// The explicit position of the actor.  Actors with an order of 0 have no
+	// explicit position.
+	Order int
+
+	// The members of a composite actor.  A composite actor only appears in
+	// views which collapse it, in which case it replaces its members.
+	Members []*Actor
+
+	// If true, actions between the members of a collapsed composite actor are
+	// removed.  Otherwise they are drawn as self references.
+	ElideInternalActions bool
+
 	rank int
 }
 
@@ -162,9 +280,11 @@ type Arrow struct {
 type NoteAlignment int
 
 const (
-	LeftNoteAlignment  NoteAlignment = iota
-	RightNoteAlignment               = iota
-	OverNoteAlignment                = iota
+	LeftNoteAlignment    NoteAlignment = iota
+	RightNoteAlignment                 = iota
+	OverNoteAlignment                  = iota
+	AcrossNoteAlignment                = iota
+	MessageNoteAlignment               = iota
 )
 
 // A sequence item
@@ -173,7 +293,8 @@ type SequenceItem interface {
 
 // Defines a note
 type Note struct {
-	// The note's alignment and position
+	// The note's alignment and position.  Notes across all actors or on a message have
+	// no actors.
 	Actor1 *Actor
 	Actor2 *Actor
 
@@ -181,6 +302,32 @@ type Note struct {
 
 	// The message
 	Message string
+
+	// The shape and colours of the note.  Blank colours use the colours of the style.
+	Shape     NoteShape
+	Color     string
+	FillColor string
+	TextColor string
+}
+
+// Note shapes
+type NoteShape int
+
+const (
+	RectNoteShape    NoteShape = iota
+	FoldedNoteShape            = iota
+	RoundedNoteShape           = iota
+	HexagonNoteShape           = iota
+	CloudNoteShape             = iota
+)
+
+// The names of the note shapes, as used by the shape attribute
+var NoteShapeNames = map[string]NoteShape{
+	"rect":    RectNoteShape,
+	"folded":  FoldedNoteShape,
+	"rounded": RoundedNoteShape,
+	"hexagon": HexagonNoteShape,
+	"cloud":   CloudNoteShape,
 }
 
 // Defines an action
@@ -196,6 +343,27 @@ type Action struct {
 
 	// The message
 	Message string
+
+	// The name of the time point marked by this action.  Can be blank.
+	TimePoint string
+
+	// A note anchored to the action.  Can be nil.
+	Note *Note
+
+	// The return of a call made with a body.  The called actor is drawn with an
+	// activation between the call and the return.  Nil for all other actions.
+	Return *Action
+}
+
+// Defines a duration constraint between two time points.  Durations are drawn
+// alongside the items between the two points.
+type Duration struct {
+	// The time points
+	From string
+	To   string
+
+	// The constraint
+	Message string
 }
 
 type DividerType int
@@ -266,6 +434,27 @@ const (
 	ConcurrentWhilstSegmentType
 )
 
+// A label marking a point in the sequence.  Labels are not drawn but can be used to
+// select the range of items to render.
+type Label struct {
+	Name string
+}
+
+// A page break.  The items following a page break are drawn on a new page when the
+// diagram is written as pages.  Page breaks are ignored when the diagram is written as
+// a single image.
+type PageBreak struct {
+}
+
+// A conditional section of sequence items.  The items are only included in the
+// diagram if the feature is enabled in the image options.  Otherwise, the else
+// items are included instead.
+type Conditional struct {
+	Feature   string
+	Items     []SequenceItem
+	ElseItems []SequenceItem
+}
+
 // A segment within a block
 type BlockSegment struct {
 	Type     SegmentType
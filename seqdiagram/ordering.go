@@ -0,0 +1,169 @@
+// Automatic ordering of actors
+//
+
+package seqdiagram
+
+import (
+	"sort"
+)
+
+// The maximum number of passes made while auto-ordering actors
+const maxAutoOrderPasses = 8
+
+// A weighted link between two actors
+type actorLink struct {
+	to     int
+	weight int
+}
+
+// Reorders the actors without an explicit order so as to reduce the total span of the
+// actions between them.  Reducing the span also reduces the number of lifelines each
+// arrow crosses.  Actors with an explicit order keep their positions.
+//
+// This starts by ordering the actors by the barycenter of the actors they
+// interact with, and then refines the order by sifting each actor into the
+// position which reduces the span the most.
+func autoOrderActors(actors []*Actor, items []SequenceItem) []*Actor {
+	index := make(map[*Actor]int)
+	for i, actor := range actors {
+		index[actor] = i
+	}
+
+	// Build the weighted links between actors
+	links := make([][]actorLink, len(actors))
+	addLink := func(a1, a2 *Actor) {
+		i1, has1 := index[a1]
+		i2, has2 := index[a2]
+		if has1 && has2 && (i1 != i2) {
+			links[i1] = append(links[i1], actorLink{i2, 1})
+			links[i2] = append(links[i2], actorLink{i1, 1})
+		}
+	}
+	walkItems(items, func(item SequenceItem) {
+		switch itemDetails := item.(type) {
+		case *Action:
+			addLink(itemDetails.From, itemDetails.To)
+		case *Note:
+			if itemDetails.Actor2 != nil {
+				addLink(itemDetails.Actor1, itemDetails.Actor2)
+			}
+		}
+	})
+
+	// Actors with an explicit order are always sorted first
+	fixed := 0
+	for fixed < len(actors) && actors[fixed].Order != 0 {
+		fixed++
+	}
+
+	// pos maps an actor index to its position; order maps a position to an actor index
+	order := make([]int, len(actors))
+	pos := make([]int, len(actors))
+	for i := range actors {
+		order[i], pos[i] = i, i
+	}
+
+	cost := func() int {
+		total := 0
+		for i, ls := range links {
+			for _, l := range ls {
+				total += l.weight * absInt(pos[i]-pos[l.to])
+			}
+		}
+		return total / 2
+	}
+	setOrder := func(newOrder []int) {
+		copy(order, newOrder)
+		for p, i := range order {
+			pos[i] = p
+		}
+	}
+
+	// Barycenter passes
+	bestCost := cost()
+	for pass := 0; pass < maxAutoOrderPasses; pass++ {
+		prevOrder := append([]int(nil), order...)
+		barycenters := make([]float64, len(actors))
+		for i, ls := range links {
+			sum, weight := 0, 0
+			for _, l := range ls {
+				sum += l.weight * pos[l.to]
+				weight += l.weight
+			}
+			if weight > 0 {
+				barycenters[i] = float64(sum) / float64(weight)
+			} else {
+				barycenters[i] = float64(pos[i])
+			}
+		}
+
+		movable := append([]int(nil), order[fixed:]...)
+		sort.SliceStable(movable, func(x, y int) bool {
+			return barycenters[movable[x]] < barycenters[movable[y]]
+		})
+		setOrder(append(order[:fixed:fixed], movable...))
+
+		if newCost := cost(); newCost < bestCost {
+			bestCost = newCost
+		} else {
+			setOrder(prevOrder)
+			break
+		}
+	}
+
+	// Sifting passes.  Each actor is slid across every movable position and moved to the
+	// one with the lowest cost.  The change in cost of swapping two adjacent actors
+	// only depends on the links of those two actors.
+	swapDelta := func(a, b int) int {
+		delta := 0
+		for _, l := range links[a] {
+			if l.to != b {
+				delta += l.weight * (absInt(pos[b]-pos[l.to]) - absInt(pos[a]-pos[l.to]))
+			}
+		}
+		for _, l := range links[b] {
+			if l.to != a {
+				delta += l.weight * (absInt(pos[a]-pos[l.to]) - absInt(pos[b]-pos[l.to]))
+			}
+		}
+		return delta
+	}
+	swap := func(p int) {
+		a, b := order[p], order[p+1]
+		order[p], order[p+1] = b, a
+		pos[a], pos[b] = p+1, p
+	}
+	for pass := 0; pass < maxAutoOrderPasses; pass++ {
+		improved := false
+		for _, a := range append([]int(nil), order[fixed:]...) {
+			delta, bestDelta, bestPos := 0, 0, pos[a]
+			for pos[a] > fixed {
+				delta += swapDelta(order[pos[a]-1], a)
+				swap(pos[a] - 1)
+				if delta < bestDelta {
+					bestDelta, bestPos = delta, pos[a]
+				}
+			}
+			for pos[a] < len(order)-1 {
+				delta += swapDelta(a, order[pos[a]+1])
+				swap(pos[a])
+				if delta < bestDelta {
+					bestDelta, bestPos = delta, pos[a]
+				}
+			}
+			for pos[a] > bestPos {
+				swap(pos[a] - 1)
+			}
+			improved = improved || (bestDelta < 0)
+		}
+		if !improved {
+			break
+		}
+	}
+
+	orderedActors := make([]*Actor, len(actors))
+	for p, i := range order {
+		orderedActors[p] = actors[i]
+	}
+	return orderedActors
+}
@@ -0,0 +1,64 @@
+package seqdiagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Parses a diagram, failing the test on an error
+func mustParseDiagram(t *testing.T, src string) *Diagram {
+	diagram, err := ParseDiagram(strings.NewReader(src), "test.seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return diagram
+}
+
+// Returns the names of the actors of the view of a diagram
+func viewActorNames(t *testing.T, diagram *Diagram, options *ImageOptions) []string {
+	view, err := diagram.viewFor(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(view.Actors))
+	for _, actor := range view.Actors {
+		names = append(names, actor.Name)
+	}
+	return names
+}
+
+func TestAutoOrderChain(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant A\nparticipant C\nparticipant D\nparticipant B\nA->B: 1\nB->C: 2\nC->D: 3\n")
+
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{}), []string{"A", "C", "D", "B"})
+
+	// The chain has the same span drawn in either direction
+	names := viewActorNames(t, diagram, &ImageOptions{AutoOrder: true})
+	if names[0] == "D" {
+		for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+			names[i], names[j] = names[j], names[i]
+		}
+	}
+	assert.Equal(names, []string{"A", "B", "C", "D"})
+}
+
+func TestAutoOrderKeepsExplicitOrder(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participants D\nparticipant A\nparticipant C\nparticipant B\nA->B: 1\nB->C: 2\nC->D: 3\n")
+
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{AutoOrder: true}), []string{"D", "C", "B", "A"})
+}
+
+func TestAutoOrderUnlinkedActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant A\nparticipant B\nparticipant C\nA->B: 1\nB->A: 2\n")
+
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{AutoOrder: true}), []string{"A", "B", "C"})
+}
@@ -0,0 +1,303 @@
+package seqdiagram
+
+import (
+	"fmt"
+	"io"
+)
+
+// Write the pages of the diagram as SVGs.  The diagram is broken into pages at each
+// page break and, if the page height is set, wherever a page would be taller than the
+// page height.  The writer of each page is opened by calling newPage with the page number,
+// starting from 1, and the number of pages.  The writer is closed once the page is written.
+func (d *Diagram) WriteSVGPages(options *ImageOptions, newPage func(page int, pages int) (io.WriteCloser, error)) error {
+	view, err := d.viewFor(options)
+	if err != nil {
+		return err
+	}
+
+	pages, err := splitPages(view, options)
+	if err != nil {
+		return err
+	}
+
+	for i, page := range pages {
+		w, err := newPage(i+1, len(pages))
+		if err != nil {
+			return err
+		}
+
+		err = page.writeSVG(w, options)
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Writes a view of the diagram as an SVG
+func (d *Diagram) writeSVG(w io.Writer, options *ImageOptions) error {
+	gb, err := newGraphicBuilder(d, options.Style)
+	if err != nil {
+		return err
+	}
+
+	graphics := gb.buildGraphic()
+	graphics.Viewport = options.Embedded
+	graphics.Scale = options.Scale
+	graphics.StyleClasses = options.StyleClasses
+	graphics.FontURL = options.FontURL
+	if options.Transparent {
+		graphics.Background = ""
+	}
+	graphics.DrawSVG(w)
+
+	return nil
+}
+
+// Splits a view into pages.  Each page has all the actors of the view, so that the
+// actors are repeated at the top and bottom of each page.  If there is more than one
+// page, the title of each page includes the page number.
+func splitPages(view *Diagram, options *ImageOptions) ([]*Diagram, error) {
+	ranges := make([][2]int, 0)
+	from, leafCount := 0, countLeaves(view.Items)
+	for _, pageBreak := range append(pageBreakLeaves(view.Items), leafCount) {
+		if options.PageHeight > 0 {
+			pageRanges, err := fitLeavesToPages(view, from, pageBreak, options)
+			if err != nil {
+				return nil, err
+			}
+			ranges = append(ranges, pageRanges...)
+		} else if pageBreak > from {
+			ranges = append(ranges, [2]int{from, pageBreak})
+		}
+		from = pageBreak + 1
+	}
+
+	if len(ranges) == 0 {
+		return []*Diagram{view}, nil
+	}
+
+	pages := make([]*Diagram, len(ranges))
+	for i, leafRange := range ranges {
+		pages[i] = pageOfView(view, leafRange[0], leafRange[1], pageTitle(view.Title, i+1, len(ranges)))
+	}
+	return pages, nil
+}
+
+// Returns the ranges of the leaves between from and to which fit within the page height.
+// Each page has at least one leaf, even if it is taller than the page height.
+func fitLeavesToPages(view *Diagram, from int, to int, options *ImageOptions) ([][2]int, error) {
+	ranges := make([][2]int, 0)
+
+	// The title is included, as the page number is added to the title of each page
+	title := pageTitle(view.Title, 1, 1)
+
+	for from < to {
+		lo, hi := from+1, to
+		for lo < hi {
+			mid := (lo + hi + 1) / 2
+			_, height, err := pageOfView(view, from, mid, title).size(options)
+			if err != nil {
+				return nil, err
+			}
+
+			if height <= options.PageHeight {
+				lo = mid
+			} else {
+				hi = mid - 1
+			}
+		}
+
+		ranges = append(ranges, [2]int{from, lo})
+		from = lo
+	}
+
+	return ranges, nil
+}
+
+// Returns the size of the diagram as it would be drawn
+func (d *Diagram) size(options *ImageOptions) (int, int, error) {
+	gb, err := newGraphicBuilder(d, options.Style)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	width, height := gb.buildGraphic().Size()
+	return width, height, nil
+}
+
+// Returns the title of a page
+func pageTitle(title string, page int, pages int) string {
+	if pages <= 1 {
+		return title
+	} else if title == "" {
+		return fmt.Sprintf("Page %d of %d", page, pages)
+	}
+	return fmt.Sprintf("%s (page %d of %d)", title, page, pages)
+}
+
+// Returns a page of the view with the leaves between from and to
+func pageOfView(view *Diagram, from int, to int, title string) *Diagram {
+	leaf := 0
+	return &Diagram{
+		Title:          title,
+		Actors:         view.Actors,
+		Items:          leavesInRange(view.Items, from, to, &leaf),
+		StyleOverrides: view.StyleOverrides,
+		continuations:  continuationsOfPage(view.Items, from, to),
+	}
+}
+
+// The calls and durations of a view which continue onto a page from the pages before or
+// after it.  These are drawn to the top or bottom of the page.
+type pageContinuations struct {
+	// The calls made before the page which have not returned by the start of the page
+	calls []*Action
+
+	// The durations of the view, and the time points marked before and after the page
+	durations        []*Duration
+	timePointsBefore map[string]bool
+	timePointsAfter  map[string]bool
+}
+
+// Returns the calls and durations which continue onto the page with the leaves between
+// from and to.  Returns without a return within the view are treated as after the page.
+func continuationsOfPage(items []SequenceItem, from int, to int) *pageContinuations {
+	continuations := &pageContinuations{
+		timePointsBefore: make(map[string]bool),
+		timePointsAfter:  make(map[string]bool),
+	}
+
+	actionLeaves := make(map[*Action]int)
+	calls := make([]*Action, 0)
+	walkLeaves(items, func(item SequenceItem, leaf int) {
+		switch itemDetails := item.(type) {
+		case *Action:
+			actionLeaves[itemDetails] = leaf
+			if itemDetails.TimePoint != "" {
+				continuations.timePointsBefore[itemDetails.TimePoint] = leaf < from
+				continuations.timePointsAfter[itemDetails.TimePoint] = leaf >= to
+			}
+			if (itemDetails.Return != nil) && (leaf < from) {
+				calls = append(calls, itemDetails)
+			}
+		case *Duration:
+			continuations.durations = append(continuations.durations, itemDetails)
+		}
+	})
+
+	for _, call := range calls {
+		if returnLeaf, hasReturn := actionLeaves[call.Return]; !hasReturn || (returnLeaf >= from) {
+			continuations.calls = append(continuations.calls, call)
+		}
+	}
+	return continuations
+}
+
+// Calls the function for each item with the index of its leaf, including the items nested
+// within blocks.  Blocks are passed with the leaf of their first segment.
+func walkLeaves(items []SequenceItem, fn func(item SequenceItem, leaf int)) {
+	leaf := 0
+
+	var walk func(items []SequenceItem)
+	walk = func(items []SequenceItem) {
+		for _, item := range items {
+			fn(item, leaf)
+			if block, isBlock := item.(*Block); isBlock {
+				for _, seg := range block.Segments {
+					leaf++
+					walk(seg.SubItems)
+				}
+			} else {
+				leaf++
+			}
+		}
+	}
+
+	walk(items)
+}
+
+// Returns the number of leaves within the items.  A leaf is an item which is not a block,
+// or the start of a block segment.  Pages are broken before a leaf.
+func countLeaves(items []SequenceItem) int {
+	leaves := 0
+	for _, item := range items {
+		if block, isBlock := item.(*Block); isBlock {
+			for _, seg := range block.Segments {
+				leaves += countLeaves(seg.SubItems) + 1
+			}
+		} else {
+			leaves++
+		}
+	}
+	return leaves
+}
+
+// Returns the indices of the leaves which are page breaks
+func pageBreakLeaves(items []SequenceItem) []int {
+	breaks := make([]int, 0)
+	leaf := 0
+
+	var findBreaks func(items []SequenceItem)
+	findBreaks = func(items []SequenceItem) {
+		for _, item := range items {
+			switch itemDetails := item.(type) {
+			case *Block:
+				for _, seg := range itemDetails.Segments {
+					leaf++
+					findBreaks(seg.SubItems)
+				}
+			case *PageBreak:
+				breaks = append(breaks, leaf)
+				leaf++
+			default:
+				leaf++
+			}
+		}
+	}
+
+	findBreaks(items)
+	return breaks
+}
+
+// Returns the items with leaves between from and to.  Blocks which straddle the range
+// are closed at the end of the page and reopened at the start of the next, keeping the
+// segments which contain leaves within the range.  Page breaks are always removed.
+func leavesInRange(items []SequenceItem, from int, to int, leaf *int) []SequenceItem {
+	rangeItems := make([]SequenceItem, 0, len(items))
+
+	for _, item := range items {
+		switch itemDetails := item.(type) {
+		case *PageBreak:
+			*leaf++
+		case *Block:
+			block := &Block{Segments: make([]*BlockSegment, 0, len(itemDetails.Segments))}
+			for _, seg := range itemDetails.Segments {
+				segStartInRange := (*leaf >= from) && (*leaf < to)
+				*leaf++
+				subItems := leavesInRange(seg.SubItems, from, to, leaf)
+				if segStartInRange || (len(subItems) > 0) {
+					newSeg := *seg
+					newSeg.SubItems = subItems
+					block.Segments = append(block.Segments, &newSeg)
+				}
+			}
+			if len(block.Segments) > 0 {
+				// The first segment determines the type of block
+				block.Segments[0].Type = itemDetails.Segments[0].Type
+				rangeItems = append(rangeItems, block)
+			}
+		default:
+			if (*leaf >= from) && (*leaf < to) {
+				rangeItems = append(rangeItems, item)
+			}
+			*leaf++
+		}
+	}
+
+	return rangeItems
+}
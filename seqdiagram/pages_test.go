@@ -0,0 +1,113 @@
+package seqdiagram
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Returns a view of a diagram with the same message repeated
+func repeatedMessagesView(t *testing.T, count int) *Diagram {
+	diagram := mustParseDiagram(t, strings.Repeat("A->B: message\n", count))
+	view, err := diagram.viewFor(&ImageOptions{Style: DefaultStyle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return view
+}
+
+// Returns the height of the page of a view with the leaves between from and to
+func pageHeight(t *testing.T, view *Diagram, from int, to int) int {
+	_, height, err := pageOfView(view, from, to, pageTitle(view.Title, 1, 1)).size(&ImageOptions{Style: DefaultStyle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return height
+}
+
+func TestFitLeavesToPages(t *testing.T) {
+	assert := assert.Assert(t)
+
+	view := repeatedMessagesView(t, 7)
+	options := &ImageOptions{Style: DefaultStyle, PageHeight: pageHeight(t, view, 0, 3)}
+
+	ranges, err := fitLeavesToPages(view, 0, 7, options)
+	assert.Equal(err, nil)
+	assert.Equal(ranges, [][2]int{{0, 3}, {3, 6}, {6, 7}})
+
+	// A page just short of the height of three leaves only fits two
+	options.PageHeight--
+	ranges, err = fitLeavesToPages(view, 0, 7, options)
+	assert.Equal(err, nil)
+	assert.Equal(ranges, [][2]int{{0, 2}, {2, 4}, {4, 6}, {6, 7}})
+}
+
+func TestFitLeavesToPagesTallerThanPage(t *testing.T) {
+	assert := assert.Assert(t)
+
+	view := repeatedMessagesView(t, 3)
+
+	// Each page has at least one leaf
+	ranges, err := fitLeavesToPages(view, 0, 3, &ImageOptions{Style: DefaultStyle, PageHeight: 1})
+	assert.Equal(err, nil)
+	assert.Equal(ranges, [][2]int{{0, 1}, {1, 2}, {2, 3}})
+}
+
+func TestSplitPagesAtPageBreaks(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "title: Flow\nA->B: 1\nA->B: 2\nnewpage\nloop: retry\n  A->B: 3\nend\n")
+	view, err := diagram.viewFor(&ImageOptions{Style: DefaultStyle})
+	assert.Equal(err, nil)
+
+	pages, err := splitPages(view, &ImageOptions{Style: DefaultStyle})
+	assert.Equal(err, nil)
+	assert.Equal(len(pages), 2)
+	assert.Equal(pages[0].Title, "Flow (page 1 of 2)")
+	assert.Equal(describeActions(pages[0].Items), []string{"A->B: 1", "A->B: 2"})
+	assert.Equal(pages[1].Title, "Flow (page 2 of 2)")
+	assert.Equal(describeActions(pages[1].Items), []string{"A->B: 3"})
+}
+
+// Returns the number of elements of the SVG of each page of a diagram with the class
+func countClassOfPages(t *testing.T, diagram *Diagram, class string) []int {
+	counts := make([]int, 0)
+	err := diagram.WriteSVGPages(&ImageOptions{Style: DefaultStyle}, func(page int, pages int) (io.WriteCloser, error) {
+		return &classCounter{class: class, counts: &counts}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+// Counts the elements of an SVG with a class once it is closed
+type classCounter struct {
+	bytes.Buffer
+	class  string
+	counts *[]int
+}
+
+func (cc *classCounter) Close() error {
+	*cc.counts = append(*cc.counts, strings.Count(cc.String(), `class="`+cc.class+`"`))
+	return nil
+}
+
+func TestActivationsContinueAcrossPages(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.call() {\n B->C: one\n newpage\n B->C: two\n newpage\n C->B: three\n return done\n}\nnewpage\nA->C: four\n")
+
+	assert.Equal(countClassOfPages(t, diagram, "activation"), []int{1, 1, 1, 0})
+}
+
+func TestDurationsContinueAcrossPages(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B (at=\"t1\"): one\nnewpage\nB->C: two\nnewpage\nB->A (at=\"t2\"): three\nnewpage\nA->C: four\nduration t1..t2: < 1s\n")
+
+	assert.Equal(countClassOfPages(t, diagram, "duration"), []int{1, 1, 1, 0})
+}
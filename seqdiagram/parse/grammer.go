@@ -1,9 +1,12 @@
+// Code generated by goyacc -o grammer.go grammer.y. DO NOT EDIT.
+
 //line grammer.y:6
 package parse
 
 import __yyfmt__ "fmt"
 
 //line grammer.y:6
+
 import (
 	"bytes"
 	"errors"
@@ -12,11 +15,13 @@ import (
 	"strconv"
 	"strings"
 	"text/scanner"
+	"unicode"
 )
 
 var DualRunes = map[string]int{
-	".": DOT,
-	",": COMMA,
+	".":  DOT,
+	"..": DOUBLEDOT,
+	",":  COMMA,
 
 	"--": DOUBLEDASH,
 	"-":  DASH,
@@ -28,7 +33,7 @@ var DualRunes = map[string]int{
 	"\\>": BACKSLASHANGR,
 }
 
-//line grammer.y:35
+//line grammer.y:37
 type yySymType struct {
 	yys          int
 	nodeList     *NodeList
@@ -42,6 +47,7 @@ type yySymType struct {
 	blockSegList *BlockSegmentList
 	attrList     *AttributeList
 	attr         *Attribute
+	identList    *IdentList
 
 	sval string
 }
@@ -69,20 +75,32 @@ const K_PAR = 57365
 const K_ELSEPAR = 57366
 const K_CONCURRENT = 57367
 const K_WHILST = 57368
-const DASH = 57369
-const DOUBLEDASH = 57370
-const DOT = 57371
-const EQUAL = 57372
-const COMMA = 57373
-const ANGR = 57374
-const DOUBLEANGR = 57375
-const BACKSLASHANGR = 57376
-const SLASHANGR = 57377
-const PARL = 57378
-const PARR = 57379
-const STRING = 57380
-const MESSAGE = 57381
-const IDENT = 57382
+const K_IF = 57369
+const K_VIEW = 57370
+const K_LABEL = 57371
+const K_DURATION = 57372
+const K_RETURN = 57373
+const K_NEWPAGE = 57374
+const K_ACROSS = 57375
+const K_PARTICIPANTS = 57376
+const DASH = 57377
+const DOUBLEDASH = 57378
+const DOT = 57379
+const EQUAL = 57380
+const COMMA = 57381
+const DOUBLEDOT = 57382
+const ANGR = 57383
+const DOUBLEANGR = 57384
+const BACKSLASHANGR = 57385
+const SLASHANGR = 57386
+const PARL = 57387
+const PARR = 57388
+const LBRACE = 57389
+const RBRACE = 57390
+const STRING = 57391
+const MESSAGE = 57392
+const CALL = 57393
+const IDENT = 57394
 
 var yyToknames = [...]string{
 	"$end",
@@ -111,34 +129,52 @@ var yyToknames = [...]string{
 	"K_ELSEPAR",
 	"K_CONCURRENT",
 	"K_WHILST",
+	"K_IF",
+	"K_VIEW",
+	"K_LABEL",
+	"K_DURATION",
+	"K_RETURN",
+	"K_NEWPAGE",
+	"K_ACROSS",
+	"K_PARTICIPANTS",
 	"DASH",
 	"DOUBLEDASH",
 	"DOT",
 	"EQUAL",
 	"COMMA",
+	"DOUBLEDOT",
 	"ANGR",
 	"DOUBLEANGR",
 	"BACKSLASHANGR",
 	"SLASHANGR",
 	"PARL",
 	"PARR",
+	"LBRACE",
+	"RBRACE",
 	"STRING",
 	"MESSAGE",
+	"CALL",
 	"IDENT",
 }
+
 var yyStatenames = [...]string{}
 
 const yyEofCode = 1
 const yyErrCode = 2
 const yyInitialStackSize = 16
 
-//line grammer.y:335
+//line grammer.y:458
 
 // Manages the lexer as well as the current diagram being parsed
 type parseState struct {
-	S     scanner.Scanner
-	err   error
-	atEof bool
+	S           scanner.Scanner
+	err         error
+	atEof       bool
+	pendingMsg  *string
+	pendingRune rune
+	lastLine    int
+	atDeclStart bool
+	braceDepth  int
 	//diagram     *Diagram
 	procInstrs []string
 	nodeList   *NodeList
@@ -148,12 +184,39 @@ func newParseState(src io.Reader, filename string) *parseState {
 	ps := &parseState{}
 	ps.S.Init(src)
 	ps.S.Position.Filename = filename
+	ps.atDeclStart = true
 	//    ps.diagram = &Diagram{}
 
 	return ps
 }
 
 func (ps *parseState) Lex(lval *yySymType) int {
+	tok := ps.lex(lval)
+
+	// Messages and braces end declarations, as does the end of the line
+	ps.atDeclStart = (tok == MESSAGE) || (tok == LBRACE) || (tok == RBRACE)
+	ps.lastLine = ps.S.Position.Line
+	return tok
+}
+
+// Returns true if the last scanned token is the first token of a declaration
+func (ps *parseState) isDeclStart() bool {
+	return ps.atDeclStart || (ps.S.Position.Line != ps.lastLine)
+}
+
+func (ps *parseState) lex(lval *yySymType) int {
+	if ps.pendingRune != 0 {
+		pendingRune := ps.pendingRune
+		ps.pendingRune = 0
+		if res, isTok := ps.handleDoubleRune(pendingRune); isTok {
+			return res
+		}
+		ps.Error("Invalid token: " + string(pendingRune))
+	}
+	if ps.pendingMsg != nil {
+		lval.sval, ps.pendingMsg = *ps.pendingMsg, nil
+		return MESSAGE
+	}
 	if ps.atEof {
 		return 0
 	}
@@ -171,7 +234,24 @@ func (ps *parseState) Lex(lval *yySymType) int {
 			return PARL
 		case ')':
 			return PARR
-		case '-', '>', '*', '=', '/', '\\', '.', ',':
+		case '{':
+			ps.braceDepth++
+			return LBRACE
+		case '}':
+			ps.braceDepth--
+			return RBRACE
+		case ';':
+			// Semicolons can be used to separate declarations on the same line
+			ps.atDeclStart = true
+		case '.':
+			if isCallNameRune(ps.S.Peek()) {
+				return ps.scanCall(lval)
+			} else if res, isTok := ps.handleDoubleRune(tok); isTok {
+				return res
+			} else {
+				ps.Error("Invalid token: " + scanner.TokenString(tok))
+			}
+		case '-', '>', '*', '=', '/', '\\', ',':
 			if res, isTok := ps.handleDoubleRune(tok); isTok {
 				return res
 			} else {
@@ -214,13 +294,19 @@ func (ps *parseState) handleDoubleRune(firstRune rune) (int, bool) {
 	return 0, false
 }
 
+// Scans a keyword or identifier.  The keywords added after the first release, such as
+// "view" and "return", are also accepted by the grammar as names so that diagrams using
+// them as participant names still parse.
 func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
 	tokVal := ps.S.TokenText()
+	lval.sval = tokVal
 	switch strings.ToLower(tokVal) {
 	case "title":
 		return K_TITLE
 	case "participant":
 		return K_PARTICIPANT
+	case "participants":
+		return K_PARTICIPANTS
 	case "note":
 		return K_NOTE
 	case "left":
@@ -229,6 +315,8 @@ func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
 		return K_RIGHT
 	case "over":
 		return K_OVER
+	case "across":
+		return K_ACROSS
 	case "of":
 		return K_OF
 	case "spacer":
@@ -263,17 +351,32 @@ func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
 		return K_CONCURRENT
 	case "whilst":
 		return K_WHILST
+	case "if":
+		return K_IF
+	case "view":
+		return K_VIEW
+	case "label":
+		return K_LABEL
+	case "newpage":
+		return K_NEWPAGE
+	case "duration":
+		return K_DURATION
+	case "return":
+		if ps.isDeclStart() {
+			ps.scanReturnValue()
+		}
+		return K_RETURN
 	default:
-		lval.sval = tokVal
 		return IDENT
 	}
 }
 
-// Scans a message.  A message is all characters up to the new line
+// Scans a message.  A message is all characters up to the new line.  Within the body of
+// a call, a message also ends at a closing brace, which can be escaped with a backslash.
 func (ps *parseState) scanMessage(lval *yySymType) int {
 	buf := new(bytes.Buffer)
-	r := ps.NextRune()
-	for (r != '\n') && (r != scanner.EOF) {
+	for r := ps.S.Peek(); (r != '\n') && (r != scanner.EOF) && ((r != '}') || (ps.braceDepth == 0)); r = ps.S.Peek() {
+		ps.NextRune()
 		if r == '\\' {
 			nr := ps.NextRune()
 			switch nr {
@@ -281,19 +384,94 @@ func (ps *parseState) scanMessage(lval *yySymType) int {
 				buf.WriteRune('\n')
 			case '\\':
 				buf.WriteRune('\\')
+			case '}':
+				buf.WriteRune('}')
 			default:
 				ps.Error("Invalid backslash escape: \\" + string(nr))
 			}
 		} else {
 			buf.WriteRune(r)
 		}
-		r = ps.NextRune()
 	}
 
 	lval.sval = strings.TrimSpace(buf.String())
 	return MESSAGE
 }
 
+// Scans a call.  A call is a name, optionally followed by an argument list
+// in parenthesis, such as "authorize(token)".
+func (ps *parseState) scanCall(lval *yySymType) int {
+	buf := new(bytes.Buffer)
+	for isCallNameRune(ps.S.Peek()) {
+		buf.WriteRune(ps.NextRune())
+	}
+
+	if ps.S.Peek() == '(' {
+		depth, inString := 0, false
+		for r := ps.S.Peek(); (r != '\n') && (r != scanner.EOF); r = ps.S.Peek() {
+			buf.WriteRune(ps.NextRune())
+			if r == '"' {
+				inString = !inString
+			} else if (r == '(') && !inString {
+				depth++
+			} else if (r == ')') && !inString {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if depth != 0 {
+			ps.Error("Unterminated argument list: " + buf.String())
+		}
+	}
+
+	lval.sval = buf.String()
+	return CALL
+}
+
+// Scans the value of a return statement.  The value is all characters up to the
+// end of the declaration, and is returned as a message by the next call to Lex.
+// Values starting with a colon are left to be scanned as an ordinary message, and values
+// starting with an arrow are scanned as a message from a participant named "return".
+func (ps *parseState) scanReturnValue() {
+	for r := ps.S.Peek(); (r == ' ') || (r == '\t'); r = ps.S.Peek() {
+		ps.NextRune()
+	}
+	if isEndOfReturnValue(ps.S.Peek()) || (ps.S.Peek() == ':') || (ps.S.Peek() == '#') {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	if r := ps.S.Peek(); (r == '-') || (r == '=') {
+		ps.NextRune()
+		if isArrowRune(r, ps.S.Peek()) {
+			ps.pendingRune = r
+			return
+		}
+		buf.WriteRune(r)
+	}
+	for !isEndOfReturnValue(ps.S.Peek()) {
+		buf.WriteRune(ps.NextRune())
+	}
+
+	value := strings.TrimSpace(buf.String())
+	ps.pendingMsg = &value
+}
+
+func isCallNameRune(r rune) bool {
+	return (r == '_') || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Returns true if the runes start an arrow
+func isArrowRune(stem rune, next rune) bool {
+	return (next == '>') || (next == '/') || (next == '\\') || ((stem == '-') && (next == '-'))
+}
+
+func isEndOfReturnValue(r rune) bool {
+	return (r == '\n') || (r == '\r') || (r == ';') || (r == '}') || (r == scanner.EOF)
+}
+
 // Scans a comment.  This ignores all characters up to the new line.
 func (ps *parseState) scanComment() {
 	var buf *bytes.Buffer
@@ -342,7 +520,10 @@ func Parse(reader io.Reader, filename string) (*NodeList, error) {
 	// Add processing instructions to the start of the node list
 	for i := len(ps.procInstrs) - 1; i >= 0; i-- {
 		instrParts := strings.SplitN(ps.procInstrs[i], " ", 2)
-		name, value := strings.TrimSpace(instrParts[0]), strings.TrimSpace(instrParts[1])
+		name, value := strings.TrimSpace(instrParts[0]), ""
+		if len(instrParts) > 1 {
+			value = strings.TrimSpace(instrParts[1])
+		}
 		ps.nodeList = &NodeList{&ProcessInstructionNode{name, value}, ps.nodeList}
 	}
 
@@ -354,114 +535,158 @@ func Parse(reader io.Reader, filename string) (*NodeList, error) {
 }
 
 //line yacctab:1
-var yyExca = [...]int{
+var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 37,
+	35, 80,
+	36, 80,
+	38, 80,
+	-2, 71,
+	-1, 39,
+	35, 79,
+	36, 79,
+	38, 79,
+	-2, 46,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 115
-
-var yyAct = [...]int{
-
-	2, 80, 73, 83, 29, 18, 15, 17, 19, 16,
-	27, 28, 27, 28, 20, 75, 34, 104, 79, 21,
-	32, 101, 99, 24, 23, 22, 78, 25, 98, 96,
-	95, 77, 76, 67, 53, 52, 51, 50, 49, 30,
-	90, 58, 26, 103, 26, 55, 64, 54, 91, 92,
-	68, 69, 70, 71, 72, 33, 60, 61, 62, 63,
-	37, 38, 89, 39, 84, 82, 81, 66, 100, 85,
-	97, 94, 87, 86, 45, 46, 47, 48, 41, 42,
-	43, 31, 57, 65, 74, 93, 56, 88, 44, 40,
-	59, 36, 35, 13, 102, 12, 105, 106, 14, 107,
-	108, 11, 109, 10, 9, 8, 7, 6, 110, 5,
-	4, 112, 111, 3, 1,
+const yyLast = 187
+
+var yyAct = [...]uint8{
+	2, 124, 91, 127, 45, 113, 27, 40, 61, 115,
+	53, 54, 55, 56, 57, 58, 44, 59, 102, 84,
+	166, 50, 48, 49, 71, 72, 73, 154, 151, 149,
+	89, 148, 52, 60, 62, 43, 119, 22, 25, 28,
+	23, 41, 42, 92, 85, 29, 86, 146, 145, 69,
+	30, 143, 141, 139, 33, 32, 31, 122, 34, 116,
+	35, 24, 36, 38, 39, 37, 44, 26, 70, 51,
+	94, 105, 101, 87, 83, 100, 82, 81, 80, 79,
+	106, 107, 108, 109, 110, 43, 46, 156, 111, 171,
+	157, 88, 96, 97, 98, 99, 90, 118, 136, 89,
+	121, 62, 117, 120, 112, 123, 89, 65, 66, 137,
+	67, 138, 134, 41, 42, 93, 133, 170, 128, 104,
+	135, 150, 140, 129, 147, 153, 152, 144, 142, 126,
+	125, 47, 53, 54, 55, 56, 57, 58, 44, 59,
+	131, 130, 103, 155, 114, 158, 159, 160, 132, 161,
+	162, 74, 163, 68, 164, 95, 64, 43, 165, 63,
+	19, 21, 167, 20, 18, 169, 168, 75, 76, 77,
+	78, 6, 8, 17, 15, 14, 16, 13, 12, 11,
+	10, 9, 7, 5, 4, 3, 1,
 }
-var yyPact = [...]int{
-
-	2, -1000, -1000, 2, -1000, -1000, -1000, -1000, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, 0, 15, -24, 33, 70,
-	61, -1, -2, -3, -4, -5, -1000, -1000, -1000, -1000,
-	-1000, 9, -1000, -1000, 9, 4, 24, -1000, -1000, -1000,
-	4, 72, 56, -1000, -6, -1000, -1000, -1000, -1000, 2,
-	2, 2, 2, 2, -1000, -25, -7, -1000, -8, -1000,
-	-1000, -1000, -1000, -1000, -13, -1000, -1000, -1000, 47, 45,
-	53, 52, 36, 3, 17, 19, -1000, -1000, -1000, 4,
-	51, -9, -10, 50, -11, -17, -1000, -1000, 48, -18,
-	-1000, -25, 5, -22, -1000, 2, 2, -1000, 2, 2,
-	-1000, 2, -1000, -1000, -1000, -1000, 47, -1000, 45, 47,
-	-1000, -1000, -1000,
+
+var yyPact = [...]int16{
+	33, -1000, -1000, 33, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	-1000, -1000, 36, 17, -17, -17, -17, 72, 16, 154,
+	29, 28, 27, 26, 24, -33, -17, -1000, -17, 23,
+	-1000, -1000, -1000, -1000, -1000, -1000, -1000, 54, -1000, -1000,
+	-1000, -1000, 54, -1000, -1000, -1000, -1000, -1000, -1000, -1000,
+	54, -1000, 76, 105, 51, -1000, -1000, -1000, 105, 54,
+	-34, 131, 108, -1000, 21, -1000, -1000, -1000, -1000, 33,
+	33, 33, 33, 33, 39, -1000, 64, -1000, -1000, -43,
+	-1000, 9, -1000, -17, -15, -1000, -1000, -1000, -1000, -1000,
+	61, 7, 54, -1000, -1000, -1000, 111, 99, 121, 120,
+	90, 33, -17, 52, 70, 73, -1000, -1000, 3, 54,
+	2, 105, -1000, 1, 107, -2, -3, 104, -19, -21,
+	-1000, -1000, 101, -22, 106, -23, -1000, -43, 38, -1000,
+	43, -1000, 54, -1000, -1000, 33, 33, -1000, 33, 33,
+	-1000, 33, -1000, 33, -1000, -1000, -1000, 33, -30, -1000,
+	111, -1000, 99, 111, 97, 41, -1000, -1000, -1000, -1000,
+	-1000, -1000,
 }
-var yyPgo = [...]int{
 
-	0, 114, 0, 113, 110, 109, 107, 106, 105, 104,
-	103, 101, 98, 95, 93, 92, 5, 91, 90, 89,
-	88, 1, 3, 87, 86, 2, 47, 84, 81,
+var yyPgo = [...]uint8{
+	0, 186, 0, 185, 184, 183, 182, 181, 180, 179,
+	178, 177, 176, 175, 174, 173, 172, 171, 164, 163,
+	161, 160, 8, 159, 6, 156, 155, 153, 151, 1,
+	3, 148, 2, 5, 43, 144, 131, 7,
 }
-var yyR1 = [...]int{
 
+var yyR1 = [...]int8{
 	0, 1, 2, 2, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 4, 5, 28, 28, 24,
-	24, 26, 25, 25, 25, 27, 6, 6, 7, 8,
-	8, 16, 16, 16, 9, 9, 10, 21, 21, 21,
-	11, 22, 22, 22, 13, 14, 12, 23, 23, 20,
-	20, 20, 20, 19, 19, 19, 15, 17, 17, 17,
-	18, 18, 18, 18,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
+	3, 3, 4, 5, 17, 36, 36, 36, 36, 32,
+	32, 34, 33, 33, 33, 35, 6, 6, 16, 22,
+	22, 7, 7, 7, 19, 19, 20, 20, 8, 8,
+	8, 8, 24, 24, 24, 9, 9, 10, 29, 29,
+	29, 11, 30, 30, 30, 13, 14, 12, 31, 31,
+	18, 21, 15, 15, 37, 37, 37, 37, 37, 37,
+	37, 37, 37, 28, 28, 28, 28, 27, 27, 27,
+	23, 25, 25, 25, 26, 26, 26, 26,
 }
-var yyR2 = [...]int{
 
+var yyR2 = [...]int8{
 	0, 1, 0, 2, 1, 1, 1, 1, 1, 1,
-	1, 1, 1, 1, 1, 2, 3, 1, 1, 0,
-	1, 3, 0, 1, 3, 3, 3, 4, 4, 4,
-	6, 1, 1, 1, 2, 3, 5, 0, 3, 4,
-	5, 0, 3, 4, 4, 4, 5, 0, 4, 1,
-	1, 1, 1, 2, 2, 1, 2, 1, 1, 1,
-	1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 2, 3, 3, 1, 1, 1, 1, 0,
+	1, 3, 0, 1, 3, 3, 3, 4, 2, 1,
+	3, 5, 5, 8, 4, 5, 1, 2, 5, 7,
+	4, 5, 1, 1, 1, 2, 3, 5, 0, 3,
+	4, 5, 0, 3, 4, 4, 4, 5, 0, 4,
+	2, 1, 5, 7, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 2, 2, 1,
+	2, 1, 1, 1, 1, 1, 1, 1,
 }
-var yyChk = [...]int{
-
-	-1000, -1, -2, -3, -4, -5, -6, -7, -8, -9,
-	-10, -11, -13, -14, -12, 4, 7, 5, -16, 6,
-	12, 17, 23, 22, 21, 25, 40, 8, 9, -2,
-	39, -28, 5, 40, 40, -15, -17, 27, 28, 30,
-	-19, 8, 9, 10, -20, 13, 14, 15, 16, 39,
-	39, 39, 39, 39, -26, 36, -24, -26, -16, -18,
-	32, 33, 34, 35, -16, 11, 11, 39, -2, -2,
-	-2, -2, -2, -25, -27, 40, 39, 39, 39, 31,
-	-21, 19, 18, -22, 19, 24, 20, 20, -23, 26,
-	37, 31, 30, -16, 20, 39, 39, 20, 39, 39,
-	20, 39, -25, 38, 39, -2, -2, -2, -2, -2,
-	-21, -22, -21,
+
+var yyChk = [...]int16{
+	-1000, -1, -2, -3, -4, -5, -17, -6, -16, -7,
+	-8, -9, -10, -11, -13, -14, -12, -15, -18, -21,
+	-19, -20, 4, 7, 28, 5, 34, -24, 6, 12,
+	17, 23, 22, 21, 25, 27, 29, 32, 30, 31,
+	-37, 8, 9, 52, 33, -2, 50, -36, 5, 6,
+	4, 52, -37, 27, 28, 29, 30, 31, 32, 34,
+	-37, -22, -37, -23, -25, 35, 36, 38, -27, 33,
+	52, 8, 9, 10, -28, 13, 14, 15, 16, 50,
+	50, 50, 50, 50, 52, -37, -37, 50, -34, 45,
+	-34, -32, -34, 39, -24, -26, 41, 42, 43, 44,
+	-24, -32, 52, 11, 11, 50, -2, -2, -2, -2,
+	-2, 49, 40, -33, -35, 52, 50, -22, -32, 51,
+	-32, 39, 50, -32, -29, 19, 18, -30, 19, 24,
+	20, 20, -31, 26, -2, -37, 46, 39, 38, 50,
+	-32, 50, -24, 50, 20, 50, 50, 20, 50, 50,
+	20, 50, 20, 19, 50, -33, 49, 47, -32, -2,
+	-2, -2, -2, -2, -2, -2, 50, -29, -30, -29,
+	20, 48,
 }
-var yyDef = [...]int{
 
+var yyDef = [...]int8{
 	2, -2, 1, 2, 4, 5, 6, 7, 8, 9,
-	10, 11, 12, 13, 14, 0, 0, 0, 0, 0,
-	0, 0, 0, 0, 0, 0, 31, 32, 33, 3,
-	15, 0, 17, 18, 19, 0, 0, 57, 58, 59,
-	0, 0, 0, 55, 34, 49, 50, 51, 52, 2,
-	2, 2, 2, 2, 16, 22, 26, 20, 0, 56,
-	60, 61, 62, 63, 0, 53, 54, 35, 37, 41,
-	0, 0, 47, 0, 23, 0, 27, 28, 29, 0,
-	0, 0, 0, 0, 0, 0, 44, 45, 0, 0,
-	21, 22, 0, 0, 36, 2, 2, 40, 2, 2,
-	46, 2, 24, 25, 30, 38, 37, 42, 41, 37,
-	39, 43, 48,
+	10, 11, 12, 13, 14, 15, 16, 17, 18, 19,
+	20, 21, 0, 0, 76, 0, 82, 0, 0, 0,
+	0, 0, 0, 0, 0, 75, 77, -2, 78, -2,
+	52, 53, 54, 74, 81, 3, 22, 0, 25, 26,
+	27, 28, 0, 75, 76, 77, 78, 79, 80, 82,
+	29, 38, 39, 0, 0, 91, 92, 93, 0, 29,
+	0, 0, 0, 89, 55, 83, 84, 85, 86, 2,
+	2, 2, 2, 2, 0, 70, 0, 47, 23, 32,
+	24, 36, 30, 0, 29, 90, 94, 95, 96, 97,
+	29, 0, 29, 87, 88, 56, 58, 62, 0, 0,
+	68, 2, 0, 0, 33, 0, 37, 40, 0, 29,
+	0, 0, 50, 0, 0, 0, 0, 0, 0, 0,
+	65, 66, 0, 0, 0, 44, 31, 32, 0, 41,
+	42, 48, 29, 51, 57, 2, 2, 61, 2, 2,
+	67, 2, 72, 2, 45, 34, 35, 2, 0, 59,
+	58, 63, 62, 58, 0, 0, 49, 60, 64, 69,
+	73, 43,
 }
-var yyTok1 = [...]int{
 
+var yyTok1 = [...]int8{
 	1,
 }
-var yyTok2 = [...]int{
 
+var yyTok2 = [...]int8{
 	2, 3, 4, 5, 6, 7, 8, 9, 10, 11,
 	12, 13, 14, 15, 16, 17, 18, 19, 20, 21,
 	22, 23, 24, 25, 26, 27, 28, 29, 30, 31,
-	32, 33, 34, 35, 36, 37, 38, 39, 40,
+	32, 33, 34, 35, 36, 37, 38, 39, 40, 41,
+	42, 43, 44, 45, 46, 47, 48, 49, 50, 51,
+	52,
 }
-var yyTok3 = [...]int{
+
+var yyTok3 = [...]int8{
 	0,
 }
 
@@ -543,9 +768,9 @@ func yyErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := yyPact[state]
+	base := int(yyPact[state])
 	for tok := TOKSTART; tok-1 < len(yyToknames); tok++ {
-		if n := base + tok; n >= 0 && n < yyLast && yyChk[yyAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < yyLast && int(yyChk[int(yyAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -555,13 +780,13 @@ func yyErrorMessage(state, lookAhead int) string {
 
 	if yyDef[state] == -2 {
 		i := 0
-		for yyExca[i] != -1 || yyExca[i+1] != state {
+		for yyExca[i] != -1 || int(yyExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; yyExca[i] >= 0; i += 2 {
-			tok := yyExca[i]
+			tok := int(yyExca[i])
 			if tok < TOKSTART || yyExca[i+1] == 0 {
 				continue
 			}
@@ -592,30 +817,30 @@ func yylex1(lex yyLexer, lval *yySymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = yyTok1[0]
+		token = int(yyTok1[0])
 		goto out
 	}
 	if char < len(yyTok1) {
-		token = yyTok1[char]
+		token = int(yyTok1[char])
 		goto out
 	}
 	if char >= yyPrivate {
 		if char < yyPrivate+len(yyTok2) {
-			token = yyTok2[char-yyPrivate]
+			token = int(yyTok2[char-yyPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(yyTok3); i += 2 {
-		token = yyTok3[i+0]
+		token = int(yyTok3[i+0])
 		if token == char {
-			token = yyTok3[i+1]
+			token = int(yyTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = yyTok2[1] /* unknown char */
+		token = int(yyTok2[1]) /* unknown char */
 	}
 	if yyDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", yyTokname(token), uint(char))
@@ -670,7 +895,7 @@ yystack:
 	yyS[yyp].yys = yystate
 
 yynewstate:
-	yyn = yyPact[yystate]
+	yyn = int(yyPact[yystate])
 	if yyn <= yyFlag {
 		goto yydefault /* simple state */
 	}
@@ -681,8 +906,8 @@ yynewstate:
 	if yyn < 0 || yyn >= yyLast {
 		goto yydefault
 	}
-	yyn = yyAct[yyn]
-	if yyChk[yyn] == yytoken { /* valid shift */
+	yyn = int(yyAct[yyn])
+	if int(yyChk[yyn]) == yytoken { /* valid shift */
 		yyrcvr.char = -1
 		yytoken = -1
 		yyVAL = yyrcvr.lval
@@ -695,7 +920,7 @@ yynewstate:
 
 yydefault:
 	/* default state action */
-	yyn = yyDef[yystate]
+	yyn = int(yyDef[yystate])
 	if yyn == -2 {
 		if yyrcvr.char < 0 {
 			yyrcvr.char, yytoken = yylex1(yylex, &yyrcvr.lval)
@@ -704,18 +929,18 @@ yydefault:
 		/* look through exception table */
 		xi := 0
 		for {
-			if yyExca[xi+0] == -1 && yyExca[xi+1] == yystate {
+			if yyExca[xi+0] == -1 && int(yyExca[xi+1]) == yystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			yyn = yyExca[xi+0]
+			yyn = int(yyExca[xi+0])
 			if yyn < 0 || yyn == yytoken {
 				break
 			}
 		}
-		yyn = yyExca[xi+1]
+		yyn = int(yyExca[xi+1])
 		if yyn < 0 {
 			goto ret0
 		}
@@ -737,10 +962,10 @@ yydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for yyp >= 0 {
-				yyn = yyPact[yyS[yyp].yys] + yyErrCode
+				yyn = int(yyPact[yyS[yyp].yys]) + yyErrCode
 				if yyn >= 0 && yyn < yyLast {
-					yystate = yyAct[yyn] /* simulate a shift of "error" */
-					if yyChk[yystate] == yyErrCode {
+					yystate = int(yyAct[yyn]) /* simulate a shift of "error" */
+					if int(yyChk[yystate]) == yyErrCode {
 						goto yystack
 					}
 				}
@@ -776,7 +1001,7 @@ yydefault:
 	yypt := yyp
 	_ = yypt // guard against "declared and not used"
 
-	yyp -= yyR2[yyn]
+	yyp -= int(yyR2[yyn])
 	// yyp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if yyp+1 >= len(yyS) {
@@ -787,16 +1012,16 @@ yydefault:
 	yyVAL = yyS[yyp+1]
 
 	/* consult goto table to find next state */
-	yyn = yyR1[yyn]
-	yyg := yyPgo[yyn]
+	yyn = int(yyR1[yyn])
+	yyg := int(yyPgo[yyn])
 	yyj := yyg + yyS[yyp].yys + 1
 
 	if yyj >= yyLast {
-		yystate = yyAct[yyg]
+		yystate = int(yyAct[yyg])
 	} else {
-		yystate = yyAct[yyj]
-		if yyChk[yystate] != -yyn {
-			yystate = yyAct[yyg]
+		yystate = int(yyAct[yyj])
+		if int(yyChk[yystate]) != -yyn {
+			yystate = int(yyAct[yyg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -804,313 +1029,424 @@ yydefault:
 
 	case 1:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:83
+//line grammer.y:93
 		{
 			yylex.(*parseState).nodeList = yyDollar[1].nodeList
 		}
 	case 2:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:90
+//line grammer.y:100
 		{
 			yyVAL.nodeList = nil
 		}
 	case 3:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:94
+//line grammer.y:104
 		{
 			yyVAL.nodeList = &NodeList{yyDollar[1].node, yyDollar[2].nodeList}
 		}
-	case 15:
+	case 22:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:115
+//line grammer.y:132
 		{
 			yyVAL.node = &TitleNode{yyDollar[2].sval}
 		}
-	case 16:
+	case 23:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:122
+//line grammer.y:139
 		{
 			yyVAL.node = &StyleNode{yyDollar[2].sval, yyDollar[3].attrList}
 		}
-	case 17:
+	case 24:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammer.y:146
+		{
+			yyVAL.node = &ViewNode{yyDollar[2].sval, yyDollar[3].attrList}
+		}
+	case 25:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:128
+//line grammer.y:152
 		{
 			yyVAL.sval = "participant"
 		}
-	case 18:
+	case 26:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammer.y:153
+		{
+			yyVAL.sval = "note"
+		}
+	case 27:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammer.y:154
+		{
+			yyVAL.sval = "title"
+		}
+	case 28:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:129
+//line grammer.y:155
 		{
 			yyVAL.sval = yyDollar[1].sval
 		}
-	case 19:
+	case 29:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:134
+//line grammer.y:160
 		{
 			yyVAL.attrList = nil
 		}
-	case 20:
+	case 30:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:138
+//line grammer.y:164
 		{
 			yyVAL.attrList = yyDollar[1].attrList
 		}
-	case 21:
+	case 31:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:145
+//line grammer.y:171
 		{
 			yyVAL.attrList = yyDollar[2].attrList
 		}
-	case 22:
+	case 32:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:152
+//line grammer.y:178
 		{
 			yyVAL.attrList = nil
 		}
-	case 23:
+	case 33:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:156
+//line grammer.y:182
 		{
 			yyVAL.attrList = &AttributeList{yyDollar[1].attr, nil}
 		}
-	case 24:
+	case 34:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:160
+//line grammer.y:186
 		{
 			yyVAL.attrList = &AttributeList{yyDollar[1].attr, yyDollar[3].attrList}
 		}
-	case 25:
+	case 35:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:167
+//line grammer.y:193
 		{
 			yyVAL.attr = &Attribute{yyDollar[1].sval, yyDollar[3].sval}
 		}
-	case 26:
+	case 36:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:174
+//line grammer.y:200
 		{
 			yyVAL.node = &ActorNode{yyDollar[2].sval, false, "", yyDollar[3].attrList}
 		}
-	case 27:
+	case 37:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:178
+//line grammer.y:204
 		{
 			yyVAL.node = &ActorNode{yyDollar[2].sval, true, yyDollar[4].sval, yyDollar[3].attrList}
 		}
-	case 28:
+	case 38:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammer.y:211
+		{
+			yyVAL.node = &ParticipantsNode{yyDollar[2].identList}
+		}
+	case 39:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammer.y:218
+		{
+			yyVAL.identList = &IdentList{yyDollar[1].sval, nil}
+		}
+	case 40:
+		yyDollar = yyS[yypt-3 : yypt+1]
+//line grammer.y:222
+		{
+			yyVAL.identList = &IdentList{yyDollar[1].sval, yyDollar[3].identList}
+		}
+	case 41:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:229
+		{
+			yyVAL.node = &ActionNode{yyDollar[1].actorRef, yyDollar[3].actorRef, yyDollar[2].arrow, yyDollar[5].sval, yyDollar[4].attrList}
+		}
+	case 42:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:233
+		{
+			yyVAL.node = &CallNode{yyDollar[1].actorRef, yyDollar[3].actorRef, yyDollar[2].arrow, yyDollar[4].sval, yyDollar[5].attrList, false, nil}
+		}
+	case 43:
+		yyDollar = yyS[yypt-8 : yypt+1]
+//line grammer.y:237
+		{
+			yyVAL.node = &CallNode{yyDollar[1].actorRef, yyDollar[3].actorRef, yyDollar[2].arrow, yyDollar[4].sval, yyDollar[5].attrList, true, yyDollar[7].nodeList}
+		}
+	case 44:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:185
+//line grammer.y:244
 		{
-			yyVAL.node = &ActionNode{yyDollar[1].actorRef, yyDollar[3].actorRef, yyDollar[2].arrow, yyDollar[4].sval}
+			yyVAL.node = &DurationNode{yyDollar[2].sval, yyDollar[4].sval, ""}
 		}
-	case 29:
+	case 45:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:248
+		{
+			yyVAL.node = &DurationNode{yyDollar[2].sval, yyDollar[4].sval, yyDollar[5].sval}
+		}
+	case 46:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammer.y:255
+		{
+			yyVAL.node = &ReturnNode{""}
+		}
+	case 47:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammer.y:259
+		{
+			yyVAL.node = &ReturnNode{yyDollar[2].sval}
+		}
+	case 48:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:266
+		{
+			yyVAL.node = &NoteNode{yyDollar[3].actorRef, nil, yyDollar[2].noteAlign, yyDollar[5].sval, yyDollar[4].attrList}
+		}
+	case 49:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line grammer.y:270
+		{
+			yyVAL.node = &NoteNode{yyDollar[3].actorRef, yyDollar[5].actorRef, yyDollar[2].noteAlign, yyDollar[7].sval, yyDollar[6].attrList}
+		}
+	case 50:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:192
+//line grammer.y:274
 		{
-			yyVAL.node = &NoteNode{yyDollar[3].actorRef, nil, yyDollar[2].noteAlign, yyDollar[4].sval}
+			yyVAL.node = &NoteNode{nil, nil, ACROSS_NOTE_ALIGNMENT, yyDollar[4].sval, yyDollar[3].attrList}
 		}
-	case 30:
-		yyDollar = yyS[yypt-6 : yypt+1]
-		//line grammer.y:196
+	case 51:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:278
 		{
-			yyVAL.node = &NoteNode{yyDollar[3].actorRef, yyDollar[5].actorRef, yyDollar[2].noteAlign, yyDollar[6].sval}
+			if !strings.EqualFold(yyDollar[2].sval, "on") || !strings.EqualFold(yyDollar[3].sval, "message") {
+				yylex.Error("Invalid note position: " + yyDollar[2].sval + " " + yyDollar[3].sval)
+			}
+			yyVAL.node = &NoteNode{nil, nil, MESSAGE_NOTE_ALIGNMENT, yyDollar[5].sval, yyDollar[4].attrList}
 		}
-	case 31:
+	case 52:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:203
+//line grammer.y:288
 		{
 			yyVAL.actorRef = NormalActorRef(yyDollar[1].sval)
 		}
-	case 32:
+	case 53:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:207
+//line grammer.y:292
 		{
 			yyVAL.actorRef = PseudoActorRef("left")
 		}
-	case 33:
+	case 54:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:211
+//line grammer.y:296
 		{
 			yyVAL.actorRef = PseudoActorRef("right")
 		}
-	case 34:
+	case 55:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:218
+//line grammer.y:303
 		{
 			yyVAL.node = &GapNode{yyDollar[2].dividerType, ""}
 		}
-	case 35:
+	case 56:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:222
+//line grammer.y:307
 		{
 			yyVAL.node = &GapNode{yyDollar[2].dividerType, yyDollar[3].sval}
 		}
-	case 36:
+	case 57:
 		yyDollar = yyS[yypt-5 : yypt+1]
-		//line grammer.y:229
+//line grammer.y:314
 		{
 			yyVAL.node = &BlockNode{&BlockSegmentList{&BlockSegment{ALT_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, yyDollar[4].blockSegList}}
 		}
-	case 37:
+	case 58:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:236
+//line grammer.y:321
 		{
 			yyVAL.blockSegList = nil
 		}
-	case 38:
+	case 59:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:240
+//line grammer.y:325
 		{
 			yyVAL.blockSegList = &BlockSegmentList{&BlockSegment{ALT_ELSE_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, nil}
 		}
-	case 39:
+	case 60:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:244
+//line grammer.y:329
 		{
 			yyVAL.blockSegList = &BlockSegmentList{&BlockSegment{ALT_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, yyDollar[4].blockSegList}
 		}
-	case 40:
+	case 61:
 		yyDollar = yyS[yypt-5 : yypt+1]
-		//line grammer.y:251
+//line grammer.y:336
 		{
 			yyVAL.node = &BlockNode{&BlockSegmentList{&BlockSegment{PAR_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, yyDollar[4].blockSegList}}
 		}
-	case 41:
+	case 62:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:258
+//line grammer.y:343
 		{
 			yyVAL.blockSegList = nil
 		}
-	case 42:
+	case 63:
 		yyDollar = yyS[yypt-3 : yypt+1]
-		//line grammer.y:262
+//line grammer.y:347
 		{
 			yyVAL.blockSegList = &BlockSegmentList{&BlockSegment{PAR_ELSE_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, nil}
 		}
-	case 43:
+	case 64:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:266
+//line grammer.y:351
 		{
 			yyVAL.blockSegList = &BlockSegmentList{&BlockSegment{PAR_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, yyDollar[4].blockSegList}
 		}
-	case 44:
+	case 65:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:273
+//line grammer.y:358
 		{
 			yyVAL.node = &BlockNode{&BlockSegmentList{&BlockSegment{OPT_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, nil}}
 		}
-	case 45:
+	case 66:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:280
+//line grammer.y:365
 		{
 			yyVAL.node = &BlockNode{&BlockSegmentList{&BlockSegment{LOOP_SEGMENT, "", yyDollar[2].sval, yyDollar[3].nodeList}, nil}}
 		}
-	case 46:
+	case 67:
 		yyDollar = yyS[yypt-5 : yypt+1]
-		//line grammer.y:287
+//line grammer.y:372
 		{
 			yyVAL.node = &BlockNode{&BlockSegmentList{&BlockSegment{CONCURRENT_SEGMENT, "", "", yyDollar[3].nodeList}, yyDollar[4].blockSegList}}
 		}
-	case 47:
+	case 68:
 		yyDollar = yyS[yypt-0 : yypt+1]
-		//line grammer.y:294
+//line grammer.y:379
 		{
 			yyVAL.blockSegList = nil
 		}
-	case 48:
+	case 69:
 		yyDollar = yyS[yypt-4 : yypt+1]
-		//line grammer.y:298
+//line grammer.y:383
 		{
 			yyVAL.blockSegList = &BlockSegmentList{&BlockSegment{CONCURRENT_WHILST_SEGMENT, "", "", yyDollar[3].nodeList}, yyDollar[4].blockSegList}
 		}
-	case 49:
+	case 70:
+		yyDollar = yyS[yypt-2 : yypt+1]
+//line grammer.y:390
+		{
+			yyVAL.node = &LabelNode{yyDollar[2].sval}
+		}
+	case 71:
+		yyDollar = yyS[yypt-1 : yypt+1]
+//line grammer.y:397
+		{
+			yyVAL.node = &PageBreakNode{}
+		}
+	case 72:
+		yyDollar = yyS[yypt-5 : yypt+1]
+//line grammer.y:404
+		{
+			yyVAL.node = &ConditionalNode{yyDollar[2].sval, yyDollar[3].sval, yyDollar[4].nodeList, nil}
+		}
+	case 73:
+		yyDollar = yyS[yypt-7 : yypt+1]
+//line grammer.y:408
+		{
+			yyVAL.node = &ConditionalNode{yyDollar[2].sval, yyDollar[3].sval, yyDollar[4].nodeList, yyDollar[6].nodeList}
+		}
+	case 83:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:304
+//line grammer.y:427
 		{
 			yyVAL.dividerType = SPACER_GAP
 		}
-	case 50:
+	case 84:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:305
+//line grammer.y:428
 		{
 			yyVAL.dividerType = EMPTY_GAP
 		}
-	case 51:
+	case 85:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:306
+//line grammer.y:429
 		{
 			yyVAL.dividerType = LINE_GAP
 		}
-	case 52:
+	case 86:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:307
+//line grammer.y:430
 		{
 			yyVAL.dividerType = FRAME_GAP
 		}
-	case 53:
+	case 87:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:311
+//line grammer.y:434
 		{
 			yyVAL.noteAlign = LEFT_NOTE_ALIGNMENT
 		}
-	case 54:
+	case 88:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:312
+//line grammer.y:435
 		{
 			yyVAL.noteAlign = RIGHT_NOTE_ALIGNMENT
 		}
-	case 55:
+	case 89:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:313
+//line grammer.y:436
 		{
 			yyVAL.noteAlign = OVER_NOTE_ALIGNMENT
 		}
-	case 56:
+	case 90:
 		yyDollar = yyS[yypt-2 : yypt+1]
-		//line grammer.y:318
+//line grammer.y:441
 		{
 			yyVAL.arrow = ArrowType{yyDollar[1].arrowStem, yyDollar[2].arrowHead}
 		}
-	case 57:
+	case 91:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:324
+//line grammer.y:447
 		{
 			yyVAL.arrowStem = SOLID_ARROW_STEM
 		}
-	case 58:
+	case 92:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:325
+//line grammer.y:448
 		{
 			yyVAL.arrowStem = DASHED_ARROW_STEM
 		}
-	case 59:
+	case 93:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:326
+//line grammer.y:449
 		{
 			yyVAL.arrowStem = THICK_ARROW_STEM
 		}
-	case 60:
+	case 94:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:330
+//line grammer.y:453
 		{
 			yyVAL.arrowHead = SOLID_ARROW_HEAD
 		}
-	case 61:
+	case 95:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:331
+//line grammer.y:454
 		{
 			yyVAL.arrowHead = OPEN_ARROW_HEAD
 		}
-	case 62:
+	case 96:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:332
+//line grammer.y:455
 		{
 			yyVAL.arrowHead = BARBED_ARROW_HEAD
 		}
-	case 63:
+	case 97:
 		yyDollar = yyS[yypt-1 : yypt+1]
-		//line grammer.y:333
+//line grammer.y:456
 		{
 			yyVAL.arrowHead = LOWER_BARBED_ARROW_HEAD
 		}
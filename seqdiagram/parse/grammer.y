@@ -13,10 +13,12 @@ import (
     "fmt"
     "strconv"
     "text/scanner"
+    "unicode"
 )
 
 var DualRunes = map[string]int {
     ".":    DOT,
+    "..":   DOUBLEDOT,
     ",":    COMMA,
 
     "--":   DOUBLEDASH,
@@ -44,6 +46,7 @@ var DualRunes = map[string]int {
     blockSegList    *BlockSegmentList
     attrList        *AttributeList
     attr            *Attribute
+    identList       *IdentList
 
     sval            string
 }
@@ -55,16 +58,23 @@ var DualRunes = map[string]int {
 %token  K_PAR K_ELSEPAR
 %token  K_CONCURRENT K_WHILST
 
+// Keywords which can also be used as names
+%token  <sval>  K_IF K_VIEW K_LABEL K_DURATION K_RETURN K_NEWPAGE K_ACROSS K_PARTICIPANTS
+
 %token  DASH    DOUBLEDASH      DOT                 EQUAL       COMMA
+%token  DOUBLEDOT
 %token  ANGR    DOUBLEANGR      BACKSLASHANGR       SLASHANGR
 %token  PARL    PARR
+%token  LBRACE  RBRACE
 
-%token  <sval>  STRING MESSAGE
+%token  <sval>  STRING MESSAGE CALL
 %token  <sval>  IDENT
 
 %type   <nodeList>      top decls
 %type   <node>          decl
 %type   <node>          title style actor action note gap altblock parblock parallelblock optblock loopblock
+%type   <node>          ifblock participants view label duration return newpage
+%type   <identList>     identlist
 %type   <arrow>         arrow
 %type   <actorRef>      actorref
 %type   <arrowStem>     arrowStem
@@ -74,7 +84,7 @@ var DualRunes = map[string]int {
 %type   <blockSegList>  altblocklist parblocklist parallelblocklist
 %type   <attrList>      maybeattrs attrs attrset
 %type   <attr>          attr
-%type   <sval>          styleidentifier
+%type   <sval>          styleidentifier name
 
 %%
 
@@ -99,7 +109,9 @@ decls
 decl
     :   title
     |   style
+    |   view
     |   actor
+    |   participants
     |   action
     |   note
     |   gap
@@ -108,6 +120,11 @@ decl
     |   optblock
     |   loopblock
     |   parallelblock
+    |   ifblock
+    |   label
+    |   newpage
+    |   duration
+    |   return
     ;
 
 title
@@ -124,8 +141,17 @@ style
     }
     ;
 
+view
+    :   K_VIEW name attrset
+    {
+        $$ = &ViewNode{$2, $3}
+    }
+    ;
+
 styleidentifier
     :   K_PARTICIPANT   { $$ = "participant"; }
+    |   K_NOTE          { $$ = "note"; }
+    |   K_TITLE         { $$ = "title"; }
     |   IDENT           { $$ = $1; }
     ;
 
@@ -170,36 +196,95 @@ attr
     ;
 
 actor
-    :   K_PARTICIPANT IDENT maybeattrs
+    :   K_PARTICIPANT name maybeattrs
     {
         $$ = &ActorNode{$2, false, "", $3}
     }
-    |   K_PARTICIPANT IDENT maybeattrs MESSAGE
+    |   K_PARTICIPANT name maybeattrs MESSAGE
     {
         $$ = &ActorNode{$2, true, $4, $3}
     }
     ;
 
+participants
+    :   K_PARTICIPANTS identlist
+    {
+        $$ = &ParticipantsNode{$2}
+    }
+    ;
+
+identlist
+    :   name
+    {
+        $$ = &IdentList{$1, nil}
+    }
+    |   name COMMA identlist
+    {
+        $$ = &IdentList{$1, $3}
+    }
+    ;
+
 action
-    :   actorref arrow actorref MESSAGE
+    :   actorref arrow actorref maybeattrs MESSAGE
+    {
+        $$ = &ActionNode{$1, $3, $2, $5, $4}
+    }
+    |   actorref arrow actorref CALL maybeattrs
+    {
+        $$ = &CallNode{$1, $3, $2, $4, $5, false, nil}
+    }
+    |   actorref arrow actorref CALL maybeattrs LBRACE decls RBRACE
+    {
+        $$ = &CallNode{$1, $3, $2, $4, $5, true, $7}
+    }
+    ;
+
+duration
+    :   K_DURATION name DOUBLEDOT name
+    {
+        $$ = &DurationNode{$2, $4, ""}
+    }
+    |   K_DURATION name DOUBLEDOT name MESSAGE
+    {
+        $$ = &DurationNode{$2, $4, $5}
+    }
+    ;
+
+return
+    :   K_RETURN
+    {
+        $$ = &ReturnNode{""}
+    }
+    |   K_RETURN MESSAGE
     {
-        $$ = &ActionNode{$1, $3, $2, $4}
+        $$ = &ReturnNode{$2}
     }
     ;
 
 note
-    :   K_NOTE noteplace actorref MESSAGE
+    :   K_NOTE noteplace actorref maybeattrs MESSAGE
+    {
+        $$ = &NoteNode{$3, nil, $2, $5, $4}
+    }
+    |   K_NOTE noteplace actorref COMMA actorref maybeattrs MESSAGE
     {
-        $$ = &NoteNode{$3, nil, $2, $4}
+        $$ = &NoteNode{$3, $5, $2, $7, $6}
     }
-    |   K_NOTE noteplace actorref COMMA actorref MESSAGE
+    |   K_NOTE K_ACROSS maybeattrs MESSAGE
     {
-        $$ = &NoteNode{$3, $5, $2, $6}
+        $$ = &NoteNode{nil, nil, ACROSS_NOTE_ALIGNMENT, $4, $3}
+    }
+    |   K_NOTE IDENT IDENT maybeattrs MESSAGE
+    {
+        if !strings.EqualFold($2, "on") || !strings.EqualFold($3, "message") {
+            yylex.Error("Invalid note position: " + $2 + " " + $3)
+        }
+        $$ = &NoteNode{nil, nil, MESSAGE_NOTE_ALIGNMENT, $5, $4}
     }
     ;
 
 actorref
-    :   IDENT
+    :   name
     {
         $$ = NormalActorRef($1)
     }
@@ -300,6 +385,44 @@ parallelblocklist
     }
     ;
 
+label
+    :   K_LABEL name
+    {
+        $$ = &LabelNode{$2}
+    }
+    ;
+
+newpage
+    :   K_NEWPAGE
+    {
+        $$ = &PageBreakNode{}
+    }
+    ;
+
+ifblock
+    :   K_IF IDENT STRING decls K_END
+    {
+        $$ = &ConditionalNode{$2, $3, $4, nil}
+    }
+    |   K_IF IDENT STRING decls K_ELSE decls K_END
+    {
+        $$ = &ConditionalNode{$2, $3, $4, $6}
+    }
+    ;
+
+// A name, which may be one of the keywords only used at the start of a declaration
+name
+    :   IDENT
+    |   K_IF
+    |   K_VIEW
+    |   K_LABEL
+    |   K_DURATION
+    |   K_RETURN
+    |   K_NEWPAGE
+    |   K_ACROSS
+    |   K_PARTICIPANTS
+    ;
+
 dividerType
     :   K_SPACER            { $$ = SPACER_GAP }
     |   K_GAP               { $$ = EMPTY_GAP }
@@ -339,6 +462,11 @@ type parseState struct {
     S           scanner.Scanner
     err         error
     atEof       bool
+    pendingMsg  *string
+    pendingRune rune
+    lastLine    int
+    atDeclStart bool
+    braceDepth  int
     //diagram     *Diagram
     procInstrs  []string
     nodeList    *NodeList
@@ -348,12 +476,39 @@ func newParseState(src io.Reader, filename string) *parseState {
     ps := &parseState{}
     ps.S.Init(src)
     ps.S.Position.Filename = filename
+    ps.atDeclStart = true
 //    ps.diagram = &Diagram{}
 
     return ps
 }
 
 func (ps *parseState) Lex(lval *yySymType) int {
+    tok := ps.lex(lval)
+
+    // Messages and braces end declarations, as does the end of the line
+    ps.atDeclStart = (tok == MESSAGE) || (tok == LBRACE) || (tok == RBRACE)
+    ps.lastLine = ps.S.Position.Line
+    return tok
+}
+
+// Returns true if the last scanned token is the first token of a declaration
+func (ps *parseState) isDeclStart() bool {
+    return ps.atDeclStart || (ps.S.Position.Line != ps.lastLine)
+}
+
+func (ps *parseState) lex(lval *yySymType) int {
+    if ps.pendingRune != 0 {
+        pendingRune := ps.pendingRune
+        ps.pendingRune = 0
+        if res, isTok := ps.handleDoubleRune(pendingRune) ; isTok {
+            return res
+        }
+        ps.Error("Invalid token: " + string(pendingRune))
+    }
+    if ps.pendingMsg != nil {
+        lval.sval, ps.pendingMsg = *ps.pendingMsg, nil
+        return MESSAGE
+    }
     if ps.atEof {
         return 0
     }
@@ -371,7 +526,24 @@ func (ps *parseState) Lex(lval *yySymType) int {
             return PARL
         case ')':
             return PARR
-        case '-', '>', '*', '=', '/', '\\', '.', ',':
+        case '{':
+            ps.braceDepth++
+            return LBRACE
+        case '}':
+            ps.braceDepth--
+            return RBRACE
+        case ';':
+            // Semicolons can be used to separate declarations on the same line
+            ps.atDeclStart = true
+        case '.':
+            if isCallNameRune(ps.S.Peek()) {
+                return ps.scanCall(lval)
+            } else if res, isTok := ps.handleDoubleRune(tok) ; isTok {
+                return res
+            } else {
+                ps.Error("Invalid token: " + scanner.TokenString(tok))
+            }
+        case '-', '>', '*', '=', '/', '\\', ',':
             if res, isTok := ps.handleDoubleRune(tok) ; isTok {
                 return res
             } else {
@@ -414,13 +586,19 @@ func (ps *parseState) handleDoubleRune(firstRune rune) (int, bool) {
     return 0, false
 }
 
+// Scans a keyword or identifier.  The keywords added after the first release, such as
+// "view" and "return", are also accepted by the grammar as names so that diagrams using
+// them as participant names still parse.
 func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
     tokVal := ps.S.TokenText()
+    lval.sval = tokVal
     switch strings.ToLower(tokVal) {
     case "title":
         return K_TITLE
     case "participant":
         return K_PARTICIPANT
+    case "participants":
+        return K_PARTICIPANTS
     case "note":
         return K_NOTE
     case "left":
@@ -429,6 +607,8 @@ func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
         return K_RIGHT
     case "over":
         return K_OVER
+    case "across":
+        return K_ACROSS
     case "of":
         return K_OF
     case "spacer":
@@ -463,17 +643,32 @@ func (ps *parseState) scanKeywordOrIdent(lval *yySymType) int {
         return K_CONCURRENT
     case "whilst":
         return K_WHILST
+    case "if":
+        return K_IF
+    case "view":
+        return K_VIEW
+    case "label":
+        return K_LABEL
+    case "newpage":
+        return K_NEWPAGE
+    case "duration":
+        return K_DURATION
+    case "return":
+        if ps.isDeclStart() {
+            ps.scanReturnValue()
+        }
+        return K_RETURN
     default:
-        lval.sval = tokVal
         return IDENT
     }
 }
 
-// Scans a message.  A message is all characters up to the new line
+// Scans a message.  A message is all characters up to the new line.  Within the body of
+// a call, a message also ends at a closing brace, which can be escaped with a backslash.
 func (ps *parseState) scanMessage(lval *yySymType) int {
     buf := new(bytes.Buffer)
-    r := ps.NextRune()
-    for ((r != '\n') && (r != scanner.EOF)) {
+    for r := ps.S.Peek() ; (r != '\n') && (r != scanner.EOF) && ((r != '}') || (ps.braceDepth == 0)) ; r = ps.S.Peek() {
+        ps.NextRune()
         if (r == '\\') {
             nr := ps.NextRune()
             switch nr {
@@ -481,19 +676,94 @@ func (ps *parseState) scanMessage(lval *yySymType) int {
                 buf.WriteRune('\n')
             case '\\':
                 buf.WriteRune('\\')
+            case '}':
+                buf.WriteRune('}')
             default:
                 ps.Error("Invalid backslash escape: \\" + string(nr))
             }
         } else {
             buf.WriteRune(r)
         }
-        r = ps.NextRune()
     }
 
     lval.sval = strings.TrimSpace(buf.String())
     return MESSAGE
 }
 
+// Scans a call.  A call is a name, optionally followed by an argument list
+// in parenthesis, such as "authorize(token)".
+func (ps *parseState) scanCall(lval *yySymType) int {
+    buf := new(bytes.Buffer)
+    for isCallNameRune(ps.S.Peek()) {
+        buf.WriteRune(ps.NextRune())
+    }
+
+    if ps.S.Peek() == '(' {
+        depth, inString := 0, false
+        for r := ps.S.Peek() ; (r != '\n') && (r != scanner.EOF) ; r = ps.S.Peek() {
+            buf.WriteRune(ps.NextRune())
+            if r == '"' {
+                inString = !inString
+            } else if (r == '(') && !inString {
+                depth++
+            } else if (r == ')') && !inString {
+                depth--
+                if depth == 0 {
+                    break
+                }
+            }
+        }
+        if depth != 0 {
+            ps.Error("Unterminated argument list: " + buf.String())
+        }
+    }
+
+    lval.sval = buf.String()
+    return CALL
+}
+
+// Scans the value of a return statement.  The value is all characters up to the
+// end of the declaration, and is returned as a message by the next call to Lex.
+// Values starting with a colon are left to be scanned as an ordinary message, and values
+// starting with an arrow are scanned as a message from a participant named "return".
+func (ps *parseState) scanReturnValue() {
+    for r := ps.S.Peek() ; (r == ' ') || (r == '\t') ; r = ps.S.Peek() {
+        ps.NextRune()
+    }
+    if isEndOfReturnValue(ps.S.Peek()) || (ps.S.Peek() == ':') || (ps.S.Peek() == '#') {
+        return
+    }
+
+    buf := new(bytes.Buffer)
+    if r := ps.S.Peek() ; (r == '-') || (r == '=') {
+        ps.NextRune()
+        if isArrowRune(r, ps.S.Peek()) {
+            ps.pendingRune = r
+            return
+        }
+        buf.WriteRune(r)
+    }
+    for !isEndOfReturnValue(ps.S.Peek()) {
+        buf.WriteRune(ps.NextRune())
+    }
+
+    value := strings.TrimSpace(buf.String())
+    ps.pendingMsg = &value
+}
+
+func isCallNameRune(r rune) bool {
+    return (r == '_') || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Returns true if the runes start an arrow
+func isArrowRune(stem rune, next rune) bool {
+    return (next == '>') || (next == '/') || (next == '\\') || ((stem == '-') && (next == '-'))
+}
+
+func isEndOfReturnValue(r rune) bool {
+    return (r == '\n') || (r == '\r') || (r == ';') || (r == '}') || (r == scanner.EOF)
+}
+
 // Scans a comment.  This ignores all characters up to the new line.
 func (ps *parseState) scanComment() {
     var buf *bytes.Buffer
@@ -543,7 +813,10 @@ func Parse(reader io.Reader, filename string) (*NodeList, error) {
     // Add processing instructions to the start of the node list
     for i := len(ps.procInstrs) - 1; i >= 0; i-- {
         instrParts := strings.SplitN(ps.procInstrs[i], " ", 2)
-        name, value := strings.TrimSpace(instrParts[0]), strings.TrimSpace(instrParts[1])
+        name, value := strings.TrimSpace(instrParts[0]), ""
+        if len(instrParts) > 1 {
+            value = strings.TrimSpace(instrParts[1])
+        }
         ps.nodeList = &NodeList{&ProcessInstructionNode{name, value}, ps.nodeList}
     }
 
@@ -0,0 +1,54 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Parses the source and returns the nodes as a slice
+func parseNodes(t *testing.T, src string) []Node {
+	nodeList, err := Parse(strings.NewReader(src), "test.seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes := make([]Node, 0)
+	for ; nodeList != nil; nodeList = nodeList.Tail {
+		nodes = append(nodes, nodeList.Head)
+	}
+	return nodes
+}
+
+func TestParseKeywordsAsParticipantNames(t *testing.T) {
+	assert := assert.Assert(t)
+
+	nodes := parseNodes(t, "Client->View: render\nView->Return: fetch\nReturn->Label: done\nparticipant If\nparticipants Across, Duration\n")
+
+	assert.Equal(len(nodes), 5)
+	assert.Equal(nodes[0].(*ActionNode).From, NormalActorRef("Client"))
+	assert.Equal(nodes[0].(*ActionNode).To, NormalActorRef("View"))
+	assert.Equal(nodes[1].(*ActionNode).From, NormalActorRef("View"))
+	assert.Equal(nodes[1].(*ActionNode).To, NormalActorRef("Return"))
+	assert.Equal(nodes[1].(*ActionNode).Descr, "fetch")
+	assert.Equal(nodes[2].(*ActionNode).From, NormalActorRef("Return"))
+	assert.Equal(nodes[2].(*ActionNode).To, NormalActorRef("Label"))
+	assert.Equal(nodes[3].(*ActorNode).Ident, "If")
+	assert.Equal(nodes[4].(*ParticipantsNode).Idents.Head, "Across")
+	assert.Equal(nodes[4].(*ParticipantsNode).Idents.Tail.Head, "Duration")
+}
+
+func TestParseKeywordsAsStatements(t *testing.T) {
+	assert := assert.Assert(t)
+
+	nodes := parseNodes(t, "label start\nreturn -1\nreturn: ok\nnewpage\nview Client (show=\"A\")\n")
+
+	assert.Equal(len(nodes), 5)
+	assert.Equal(nodes[0].(*LabelNode).Name, "start")
+	assert.Equal(nodes[1].(*ReturnNode).Descr, "-1")
+	assert.Equal(nodes[2].(*ReturnNode).Descr, "ok")
+	_, isPageBreak := nodes[3].(*PageBreakNode)
+	assert.Equal(isPageBreak, true)
+	assert.Equal(nodes[4].(*ViewNode).Name, "Client")
+}
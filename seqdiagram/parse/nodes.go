@@ -64,6 +64,12 @@ type StyleNode struct {
 	Attributes *AttributeList
 }
 
+// A view declaration node
+type ViewNode struct {
+	Name       string
+	Attributes *AttributeList
+}
+
 // An actor declaration node
 type ActorNode struct {
 	// Identifier
@@ -89,6 +95,17 @@ func (an *ActorNode) ActorName() string {
 	}
 }
 
+// A list of identifiers
+type IdentList struct {
+	Head string
+	Tail *IdentList
+}
+
+// A participants declaration node.  This declares the order of the listed actors.
+type ParticipantsNode struct {
+	Idents *IdentList
+}
+
 // A reference to an actor
 type ActorRef interface {
 }
@@ -101,9 +118,34 @@ type PseudoActorRef string
 
 // An action node
 type ActionNode struct {
-	From  ActorRef
-	To    ActorRef
-	Arrow ArrowType
+	From       ActorRef
+	To         ActorRef
+	Arrow      ArrowType
+	Descr      string
+	Attributes *AttributeList
+}
+
+// A call node.  This is an action written as a call, optionally with a body of
+// declarations which take place during the call.
+type CallNode struct {
+	From       ActorRef
+	To         ActorRef
+	Arrow      ArrowType
+	Call       string
+	Attributes *AttributeList
+	HasBody    bool
+	SubNodes   *NodeList
+}
+
+// A duration node.  This constrains the duration between two time points.
+type DurationNode struct {
+	From  string
+	To    string
+	Descr string
+}
+
+// A return node.  This returns from the most recent call which has not been returned.
+type ReturnNode struct {
 	Descr string
 }
 
@@ -111,17 +153,20 @@ type ActionNode struct {
 type NoteAlignment int
 
 const (
-	LEFT_NOTE_ALIGNMENT  NoteAlignment = iota
-	RIGHT_NOTE_ALIGNMENT               = iota
-	OVER_NOTE_ALIGNMENT                = iota
+	LEFT_NOTE_ALIGNMENT    NoteAlignment = iota
+	RIGHT_NOTE_ALIGNMENT                 = iota
+	OVER_NOTE_ALIGNMENT                  = iota
+	ACROSS_NOTE_ALIGNMENT                = iota
+	MESSAGE_NOTE_ALIGNMENT               = iota
 )
 
 type NoteNode struct {
-	Actor1 ActorRef
+	Actor1 ActorRef // Nil for notes across all actors or on a message
 	Actor2 ActorRef // Can be nil
 
-	Position NoteAlignment
-	Descr    string
+	Position   NoteAlignment
+	Descr      string
+	Attributes *AttributeList
 }
 
 // Gap node
@@ -156,6 +201,27 @@ type BlockSegment struct {
 	SubNodes *NodeList
 }
 
+// A label node.  This marks a point in the sequence.
+type LabelNode struct {
+	Name string
+}
+
+// A page break node.  This starts a new page.
+type PageBreakNode struct {
+}
+
+// A conditional node.  The sub nodes are only included if the condition holds.
+type ConditionalNode struct {
+	// The type of condition (e.g. "feature")
+	Kind string
+
+	// The condition argument (e.g. the feature name)
+	Arg string
+
+	SubNodes     *NodeList
+	ElseSubNodes *NodeList
+}
+
 // Attributes
 type Attribute struct {
 	Name  string
@@ -0,0 +1,165 @@
+package seqdiagram
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// The names of the arrow heads, as used in style files
+var arrowHeadStyleNames = map[string]ArrowHead{
+	"solid":     SolidArrowHead,
+	"open":      OpenArrowHead,
+	"barb":      BarbArrowHead,
+	"lowerbarb": LowerBarbArrowHead,
+}
+
+// The names of the divider types, as used in style files
+var dividerStyleNames = map[string]DividerType{
+	"spacer": DTSpacer,
+	"gap":    DTGap,
+	"frame":  DTFrame,
+	"line":   DTLine,
+}
+
+// The parts of a style file which are not decoded directly into the styles
+type styleFileExtras struct {
+	// The name of the built-in style to inherit from.  Defaults to "default".
+	Base string
+
+	// The arrow heads and dividers, keyed by name
+	ArrowHeads map[string]json.RawMessage
+	Dividers   map[string]json.RawMessage
+
+	// The font files of the parts of the diagram, keyed by the part
+	Fonts map[string]string
+}
+
+// The fields of a style file.  Decoding a style file into this rejects fields which
+// are neither styles nor extras.
+type styleFile struct {
+	*DiagramStyles
+	styleFileExtras
+}
+
+// Decodes JSON, rejecting the fields which are not in the value
+func decodeStrictJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// LoadStyle reads diagram styles from a JSON style file.  The styles inherit from a
+// built-in style, named by the "base" field, and the fields of the file override those
+// of the base style.  The fields are named after the fields of DiagramStyles:
+//
+//	{
+//	    "base": "tight",
+//	    "ForegroundColor": "#333",
+//	    "ActivityLine": { "FontSize": 12, "Margin": { "X": 16, "Y": 4 } },
+//	    "ArrowHeads": { "solid": { "Xs": [-9, 0, -9], "Ys": [-5, 0, 5] } },
+//	    "Dividers": { "line": { "Shape": "framedrect" } },
+//	    "Fonts": { "diagram": "Inter.ttf", "title": "Inter-Bold.ttf" },
+//	    "FallbackFonts": [ "DroidSansFallback.ttf", "NotoEmoji.ttf" ]
+//	}
+//
+// Arrow heads are named solid, open, barb and lowerbarb, and dividers spacer, gap,
+// frame and line.  Fonts are set for the diagram, title, message and note parts, with
+// relative font files, including the fallback fonts, loaded from the working directory.
+func LoadStyle(r io.Reader) (*DiagramStyles, error) {
+	return loadStyle(r, "")
+}
+
+// Reads diagram styles from a JSON style file, loading relative font files from a directory
+func loadStyle(r io.Reader, dir string) (*DiagramStyles, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var extras styleFileExtras
+	if err := json.Unmarshal(data, &extras); err != nil {
+		return nil, errors.New("Invalid style file: " + err.Error())
+	}
+
+	if extras.Base == "" {
+		extras.Base = "default"
+	}
+	base, hasBase := StyleNames[extras.Base]
+	if !hasBase {
+		return nil, errors.New("Unrecognised base style: " + extras.Base)
+	}
+
+	// Decoding over a copy of the base style keeps the fields which are not in the file
+	styles := base.Copy()
+	if err := decodeStrictJSON(data, &styleFile{DiagramStyles: styles}); err != nil {
+		return nil, errors.New("Invalid style file: " + err.Error())
+	}
+
+	for name, data := range extras.ArrowHeads {
+		head, hasHead := arrowHeadStyleNames[name]
+		if !hasHead {
+			return nil, errors.New("Unrecognised arrow head: " + name)
+		}
+
+		headStyle := styles.ArrowHeads[head]
+		if err := decodeStrictJSON(data, headStyle); err != nil {
+			return nil, errors.New("Invalid style for arrow head " + name + ": " + err.Error())
+		} else if (len(headStyle.Xs) == 0) || (len(headStyle.Xs) != len(headStyle.Ys)) {
+			return nil, errors.New("Invalid style for arrow head " + name + ": Xs and Ys must have the same number of points")
+		}
+	}
+
+	for name, data := range extras.Dividers {
+		dividerType, hasDivider := dividerStyleNames[name]
+		if !hasDivider {
+			return nil, errors.New("Unrecognised divider: " + name)
+		}
+
+		dividerStyle := styles.Divider[dividerType]
+		if err := decodeStrictJSON(data, &dividerStyle); err != nil {
+			return nil, errors.New("Invalid style for divider " + name + ": " + err.Error())
+		}
+		styles.Divider[dividerType] = dividerStyle
+	}
+
+	if len(styles.FallbackFonts) > 0 {
+		fallbackFonts := make([]string, len(styles.FallbackFonts))
+		for i, fontFile := range styles.FallbackFonts {
+			if !filepath.IsAbs(fontFile) {
+				fontFile = filepath.Join(dir, fontFile)
+			}
+			fallbackFonts[i] = fontFile
+		}
+		styles.FallbackFonts = fallbackFonts
+	}
+
+	if len(extras.Fonts) > 0 {
+		fontFiles := make(map[string]string, len(extras.Fonts))
+		for part, fontFile := range extras.Fonts {
+			if !filepath.IsAbs(fontFile) {
+				fontFile = filepath.Join(dir, fontFile)
+			}
+			fontFiles[part] = fontFile
+		}
+		return styles.WithFontFiles(fontFiles)
+	}
+
+	return styles, nil
+}
+
+// LoadStyleFile reads diagram styles from a JSON style file on disk.  Relative font files
+// are loaded from the directory of the style file.
+func LoadStyleFile(filename string) (*DiagramStyles, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return loadStyle(file, filepath.Dir(filename))
+}
@@ -0,0 +1,67 @@
+package seqdiagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+func TestLoadStyle(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles, err := LoadStyle(strings.NewReader(`{
+		"base": "tight",
+		"ForegroundColor": "red",
+		"ArrowHeads": { "solid": { "Xs": [-12, 0, -12], "Ys": [-4, 0, 4] } }
+	}`))
+
+	assert.Equal(err, nil)
+	assert.Equal(styles.ForegroundColor, "red")
+	assert.Equal(styles.ArrowHeads[SolidArrowHead].Xs, []int{-12, 0, -12})
+	assert.Equal(styles.ArrowHeads[OpenArrowHead].Xs, TightStyle.ArrowHeads[OpenArrowHead].Xs)
+}
+
+func TestLoadStyleInvalidFields(t *testing.T) {
+	for _, src := range []string{
+		`{ "ForgroundColor": "red" }`,
+		`{ "ActivityLine": { "FontSise": 12 } }`,
+		`{ "ArrowHeads": { "solid": { "Xs": [-12, 0, -12], "Ys": [-4, 0] } } }`,
+		`{ "ArrowHeads": { "solid": { "Xs": [-12, 0, -12], "Ys": [] } } }`,
+		`{ "ArrowHeads": { "solid": { "Xs": [], "Ys": [] } } }`,
+		`{ "ArrowHeads": { "solid": { "Colour": "red" } } }`,
+		`{ "Dividers": { "line": { "Shap": "framedrect" } } }`,
+	} {
+		_, err := LoadStyle(strings.NewReader(src))
+		if err == nil {
+			t.Errorf("Expected an error loading %s", src)
+		}
+	}
+}
+
+func TestLoadStyleKeepsBaseStyle(t *testing.T) {
+	assert := assert.Assert(t)
+
+	xs := append([]int(nil), TightStyle.ArrowHeads[SolidArrowHead].Xs...)
+	ys := append([]int(nil), TightStyle.ArrowHeads[SolidArrowHead].Ys...)
+
+	_, err := LoadStyle(strings.NewReader(`{
+		"base": "tight",
+		"ArrowHeads": { "solid": { "Xs": [-20, 0, -20], "Ys": [-8, 0, 8] } }
+	}`))
+
+	assert.Equal(err, nil)
+	assert.Equal(TightStyle.ArrowHeads[SolidArrowHead].Xs, xs)
+	assert.Equal(TightStyle.ArrowHeads[SolidArrowHead].Ys, ys)
+}
+
+func TestCopyStyles(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles := DefaultStyle.Copy()
+	styles.ArrowHeads[SolidArrowHead].Xs[0] = -100
+	styles.FallbackFonts = append(styles.FallbackFonts, "Fallback.ttf")
+
+	assert.Equal(DefaultStyle.ArrowHeads[SolidArrowHead].Xs[0] != -100, true)
+	assert.Equal(len(DefaultStyle.FallbackFonts), 0)
+}
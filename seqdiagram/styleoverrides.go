@@ -0,0 +1,151 @@
+package seqdiagram
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lmika/goseq/seqdiagram/graphbox"
+)
+
+// Sets a field of the diagram styles from the value of a style attribute
+type styleOverride func(styles *DiagramStyles, value string) error
+
+// The attributes of the style statements which override the diagram styles, keyed by
+// the style identifier and then the attribute name.
+var styleOverrides = map[string]map[string]styleOverride{
+	"diagram": {
+		"margin":        pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.Margin }),
+		"color":         colorOverride(func(s *DiagramStyles) *string { return &s.ForegroundColor }),
+		"background":    colorOverride(func(s *DiagramStyles) *string { return &s.BackgroundColor }),
+		"knockout":      colorOverride(func(s *DiagramStyles) *string { return &s.KnockoutColor }),
+		"notefill":      colorOverride(func(s *DiagramStyles) *string { return &s.NoteFillColor }),
+		"blockcolor":    colorOverride(func(s *DiagramStyles) *string { return &s.BlockColor }),
+		"font":          fontOverride("diagram"),
+		"fallbackfonts": fallbackFontsOverride,
+	},
+	styleIdentifierNote: {
+		"fontsize": intOverride(func(s *DiagramStyles) *int { return &s.NoteBox.FontSize }),
+		"padding":  pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.NoteBox.Padding }),
+		"margin":   pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.NoteBox.Margin }),
+		"overlap":  intOverride(func(s *DiagramStyles) *int { return &s.MultiNoteOverlap }),
+		"font":     fontOverride("note"),
+	},
+	"arrow": {
+		"fontsize":   intOverride(func(s *DiagramStyles) *int { return &s.ActivityLine.FontSize }),
+		"margin":     pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.ActivityLine.Margin }),
+		"textgap":    intOverride(func(s *DiagramStyles) *int { return &s.ActivityLine.TextGap }),
+		"selfwidth":  intOverride(func(s *DiagramStyles) *int { return &s.ActivityLine.SelfRefWidth }),
+		"selfheight": intOverride(func(s *DiagramStyles) *int { return &s.ActivityLine.SelfRefHeight }),
+		"color":      colorOverride(func(s *DiagramStyles) *string { return &s.ActivityLine.Color }),
+		"font":       fontOverride("message"),
+	},
+	"block": {
+		"fontsize":       intOverride(func(s *DiagramStyles) *int { return &s.Block.FontSize }),
+		"margin":         pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.Block.Margin }),
+		"textpadding":    pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.Block.TextPadding }),
+		"messagepadding": pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.Block.MessagePadding }),
+		"color":          colorOverride(func(s *DiagramStyles) *string { return &s.Block.Color }),
+		"textcolor":      colorOverride(func(s *DiagramStyles) *string { return &s.Block.TextColor }),
+	},
+	"title": {
+		"fontsize": intOverride(func(s *DiagramStyles) *int { return &s.Title.FontSize }),
+		"padding":  pointOverride(func(s *DiagramStyles) *graphbox.Point { return &s.Title.Padding }),
+		"color":    colorOverride(func(s *DiagramStyles) *string { return &s.Title.Color }),
+		"font":     fontOverride("title"),
+	},
+}
+
+// Returns an override which sets an integer field.  The value cannot be negative.
+func intOverride(field func(s *DiagramStyles) *int) styleOverride {
+	return func(styles *DiagramStyles, value string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if (err != nil) || (n < 0) {
+			return errors.New("Invalid size: " + value)
+		}
+		*field(styles) = n
+		return nil
+	}
+}
+
+// Returns an override which sets a point field.  The value is either "x,y" or a single
+// number for both.
+func pointOverride(field func(s *DiagramStyles) *graphbox.Point) styleOverride {
+	return func(styles *DiagramStyles, value string) error {
+		parts := strings.Split(value, ",")
+		if len(parts) > 2 {
+			return errors.New("Invalid size: " + value)
+		}
+
+		sizes := make([]int, len(parts))
+		for i, part := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if (err != nil) || (n < 0) {
+				return errors.New("Invalid size: " + value)
+			}
+			sizes[i] = n
+		}
+
+		*field(styles) = graphbox.Point{sizes[0], sizes[len(sizes)-1]}
+		return nil
+	}
+}
+
+// Returns an override which sets a colour field
+func colorOverride(field func(s *DiagramStyles) *string) styleOverride {
+	return func(styles *DiagramStyles, value string) error {
+		*field(styles) = value
+		return nil
+	}
+}
+
+// Returns an override which sets the font of a part of the diagram from a font file
+func fontOverride(part string) styleOverride {
+	return func(styles *DiagramStyles, value string) error {
+		font, err := LoadFontFile(value)
+		if err != nil {
+			return err
+		}
+		fontParts[part](styles, font)
+		return nil
+	}
+}
+
+// Sets the fallback fonts from a comma separated list of font files
+func fallbackFontsOverride(styles *DiagramStyles, value string) error {
+	fontFiles := SplitList(value)
+	for _, fontFile := range fontFiles {
+		if _, err := LoadFontFile(fontFile); err != nil {
+			return err
+		}
+	}
+	styles.FallbackFonts = fontFiles
+	return nil
+}
+
+// Returns a copy of the styles with the overrides declared in the diagram applied.  The
+// overrides are keyed by the style identifier and then the attribute name.  The diagram
+// overrides are applied first, so that the other style identifiers can override them.
+func (ds *DiagramStyles) withOverrides(overrides map[string]map[string]string) (*DiagramStyles, error) {
+	if len(overrides) == 0 {
+		return ds, nil
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sortDiagramFirst(names)
+
+	styles := ds.Copy()
+	for _, name := range names {
+		for attrName, value := range overrides[name] {
+			if override, hasOverride := styleOverrides[name][attrName]; hasOverride {
+				if err := override(styles, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return styles, nil
+}
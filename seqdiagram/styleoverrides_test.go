@@ -0,0 +1,123 @@
+package seqdiagram
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lmika/goseq/seqdiagram/graphbox"
+	"github.com/seanpont/assert"
+)
+
+func TestStyleOverrides(t *testing.T) {
+	fontFile := writeFallbackFont(t)
+
+	for _, test := range []struct {
+		style, attr, value string
+		field              func(s *DiagramStyles) interface{}
+		expected           interface{}
+	}{
+		{"diagram", "margin", "16", func(s *DiagramStyles) interface{} { return s.Margin }, graphbox.Point{16, 16}},
+		{"diagram", "margin", "16, 8", func(s *DiagramStyles) interface{} { return s.Margin }, graphbox.Point{16, 8}},
+		{"diagram", "color", "#333", func(s *DiagramStyles) interface{} { return s.ForegroundColor }, "#333"},
+		{"diagram", "background", "white", func(s *DiagramStyles) interface{} { return s.BackgroundColor }, "white"},
+		{"diagram", "knockout", "black", func(s *DiagramStyles) interface{} { return s.KnockoutColor }, "black"},
+		{"diagram", "notefill", "#ffe", func(s *DiagramStyles) interface{} { return s.NoteFillColor }, "#ffe"},
+		{"diagram", "blockcolor", "gray", func(s *DiagramStyles) interface{} { return s.BlockColor }, "gray"},
+		{"diagram", "font", fontFile, func(s *DiagramStyles) interface{} { return s.ActorBox.Font.SvgName() }, "Go,sans-serif"},
+		{"diagram", "fallbackfonts", fontFile, func(s *DiagramStyles) interface{} { return s.FallbackFonts }, []string{fontFile}},
+		{"note", "fontsize", "12", func(s *DiagramStyles) interface{} { return s.NoteBox.FontSize }, 12},
+		{"note", "padding", "12,6", func(s *DiagramStyles) interface{} { return s.NoteBox.Padding }, graphbox.Point{12, 6}},
+		{"note", "margin", "4", func(s *DiagramStyles) interface{} { return s.NoteBox.Margin }, graphbox.Point{4, 4}},
+		{"note", "overlap", "10", func(s *DiagramStyles) interface{} { return s.MultiNoteOverlap }, 10},
+		{"note", "font", fontFile, func(s *DiagramStyles) interface{} { return s.NoteBox.Font.SvgName() }, "Go,sans-serif"},
+		{"arrow", "fontsize", "11", func(s *DiagramStyles) interface{} { return s.ActivityLine.FontSize }, 11},
+		{"arrow", "margin", "16,4", func(s *DiagramStyles) interface{} { return s.ActivityLine.Margin }, graphbox.Point{16, 4}},
+		{"arrow", "textgap", "2", func(s *DiagramStyles) interface{} { return s.ActivityLine.TextGap }, 2},
+		{"arrow", "selfwidth", "32", func(s *DiagramStyles) interface{} { return s.ActivityLine.SelfRefWidth }, 32},
+		{"arrow", "selfheight", "16", func(s *DiagramStyles) interface{} { return s.ActivityLine.SelfRefHeight }, 16},
+		{"arrow", "color", "navy", func(s *DiagramStyles) interface{} { return s.ActivityLine.Color }, "navy"},
+		{"arrow", "font", fontFile, func(s *DiagramStyles) interface{} { return s.ActivityLine.Font.SvgName() }, "Go,sans-serif"},
+		{"block", "fontsize", "10", func(s *DiagramStyles) interface{} { return s.Block.FontSize }, 10},
+		{"block", "margin", "8", func(s *DiagramStyles) interface{} { return s.Block.Margin }, graphbox.Point{8, 8}},
+		{"block", "textpadding", "4,2", func(s *DiagramStyles) interface{} { return s.Block.TextPadding }, graphbox.Point{4, 2}},
+		{"block", "messagepadding", "6,3", func(s *DiagramStyles) interface{} { return s.Block.MessagePadding }, graphbox.Point{6, 3}},
+		{"block", "color", "gray", func(s *DiagramStyles) interface{} { return s.Block.Color }, "gray"},
+		{"block", "textcolor", "black", func(s *DiagramStyles) interface{} { return s.Block.TextColor }, "black"},
+		{"title", "fontsize", "28", func(s *DiagramStyles) interface{} { return s.Title.FontSize }, 28},
+		{"title", "padding", "8", func(s *DiagramStyles) interface{} { return s.Title.Padding }, graphbox.Point{8, 8}},
+		{"title", "color", "navy", func(s *DiagramStyles) interface{} { return s.Title.Color }, "navy"},
+		{"title", "font", fontFile, func(s *DiagramStyles) interface{} { return s.Title.Font.SvgName() }, "Go,sans-serif"},
+	} {
+		styles, err := DefaultStyle.withOverrides(map[string]map[string]string{
+			test.style: {test.attr: test.value},
+		})
+		if err != nil {
+			t.Errorf("style %s (%s=%q): %v", test.style, test.attr, test.value, err)
+			continue
+		}
+
+		assert.Assert(t).Equal(test.field(styles), test.expected)
+	}
+}
+
+func TestStyleOverridesKeepBaseStyle(t *testing.T) {
+	assert := assert.Assert(t)
+
+	margin := DefaultStyle.Margin
+	styles, err := DefaultStyle.withOverrides(map[string]map[string]string{
+		"diagram": {"margin": "99"},
+	})
+
+	assert.Equal(err, nil)
+	assert.Equal(styles.Margin, graphbox.Point{99, 99})
+	assert.Equal(DefaultStyle.Margin, margin)
+}
+
+func TestStyleOverridesAppliedAfterDiagram(t *testing.T) {
+	assert := assert.Assert(t)
+
+	styles, err := DefaultStyle.withOverrides(map[string]map[string]string{
+		"arrow":   {"color": "navy"},
+		"diagram": {"color": "#333"},
+	})
+
+	assert.Equal(err, nil)
+	assert.Equal(styles.ForegroundColor, "#333")
+	assert.Equal(styles.ActivityLine.Color, "navy")
+}
+
+func TestAddStyle(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "style diagram (margin=\"16\", color=\"#333\")\n"+
+		"style note (fontsize=\"12\", shape=\"folded\")\nstyle title (fontsize=\"28\")\nA->B: Hi\n")
+
+	assert.Equal(diagram.StyleOverrides, map[string]map[string]string{
+		"diagram": {"margin": "16", "color": "#333"},
+		"note":    {"fontsize": "12"},
+		"title":   {"fontsize": "28"},
+	})
+}
+
+func TestAddStyleInvalidAttributes(t *testing.T) {
+	missingFont := filepath.Join(t.TempDir(), "Missing.ttf")
+
+	for _, test := range []struct {
+		src, message string
+	}{
+		{`style diagram (colour="red")`, "Unrecognised diagram style attribute: colour"},
+		{`style arrow (shape="folded")`, "Unrecognised arrow style attribute: shape"},
+		{`style block (fontsize="big")`, "Invalid size: big"},
+		{`style note (fontsize="-2")`, "Invalid size: -2"},
+		{`style title (padding="1,2,3")`, "Invalid size: 1,2,3"},
+		{`style arrow (margin="4,x")`, "Invalid size: 4,x"},
+		{`style title (font="` + missingFont + `")`, "Cannot load font " + missingFont},
+		{`style diagram (fallbackfonts="` + missingFont + `")`, "Cannot load font " + missingFont},
+	} {
+		_, err := ParseDiagram(strings.NewReader(test.src+"\nA->B: Hi\n"), "test.seq")
+		if (err == nil) || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%s: expected error %q, got %v", test.src, test.message, err)
+		}
+	}
+}
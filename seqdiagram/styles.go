@@ -21,8 +21,8 @@ type DiagramStyles struct {
 	// Styling of the activity line
 	ActivityLine graphbox.ActivityLineStyle
 
-	// Styling of arrow heads
-	ArrowHeads map[ArrowHead]*graphbox.ArrowHeadStyle
+	// Styling of arrow heads.  These are keyed by name in style files.
+	ArrowHeads map[ArrowHead]*graphbox.ArrowHeadStyle `json:"-"`
 
 	// Styling of the diagram title
 	Title graphbox.TitleStyle
@@ -30,8 +30,142 @@ type DiagramStyles struct {
 	// Block styling
 	Block graphbox.BlockStyle
 
-	// Styles of dividers
-	Divider map[DividerType]graphbox.DividerStyle
+	// Styles of dividers.  These are keyed by name in style files.
+	Divider map[DividerType]graphbox.DividerStyle `json:"-"`
+
+	// Style of the duration constraints
+	Duration graphbox.DurationLineStyle
+
+	// Style of the activation bars of calls
+	Activation graphbox.ActivationStyle
+
+	// Colours of the diagram.  The foreground colour is used for lines and text, and
+	// the knockout colour for the shapes drawn over lines, such as the rectangles behind
+	// text and the actor boxes.  If the background colour is blank, the background is
+	// transparent.  Colours set in the styles of the items take precedence.
+	ForegroundColor string
+	BackgroundColor string
+	NoteFillColor   string
+	BlockColor      string
+	KnockoutColor   string
+
+	// Font files of the fallback fonts.  Runes which the font of an item has no glyph for,
+	// such as CJK characters, emoji or symbols, are drawn with the first fallback font
+	// which has a glyph for them.
+	FallbackFonts []string
+}
+
+// Returns a copy of the styles.  The arrow heads, dividers and fallback fonts are copied,
+// so that they can be changed without affecting the original styles.
+func (ds *DiagramStyles) Copy() *DiagramStyles {
+	styles := *ds
+
+	styles.ArrowHeads = make(map[ArrowHead]*graphbox.ArrowHeadStyle)
+	for head, headStyle := range ds.ArrowHeads {
+		headStyleCopy := *headStyle
+		headStyleCopy.Xs = append([]int(nil), headStyle.Xs...)
+		headStyleCopy.Ys = append([]int(nil), headStyle.Ys...)
+		styles.ArrowHeads[head] = &headStyleCopy
+	}
+
+	styles.Divider = make(map[DividerType]graphbox.DividerStyle)
+	for dividerType, dividerStyle := range ds.Divider {
+		styles.Divider[dividerType] = dividerStyle
+	}
+
+	styles.FallbackFonts = append([]string(nil), ds.FallbackFonts...)
+
+	return &styles
+}
+
+// Sets the font of every part of the diagram
+func (ds *DiagramStyles) setFont(font graphbox.Font) {
+	ds.mapFonts(func(graphbox.Font) graphbox.Font { return font })
+}
+
+// Replaces the font of every part of the diagram with the result of a function
+func (ds *DiagramStyles) mapFonts(fn func(font graphbox.Font) graphbox.Font) {
+	ds.ActorBox.Font = fn(ds.ActorBox.Font)
+	ds.ActorIconBox.Font = fn(ds.ActorIconBox.Font)
+	ds.NoteBox.Font = fn(ds.NoteBox.Font)
+	ds.ActivityLine.Font = fn(ds.ActivityLine.Font)
+	ds.Title.Font = fn(ds.Title.Font)
+	ds.Block.Font = fn(ds.Block.Font)
+	ds.Duration.Font = fn(ds.Duration.Font)
+
+	for dividerType, dividerStyle := range ds.Divider {
+		dividerStyle.Font = fn(dividerStyle.Font)
+		ds.Divider[dividerType] = dividerStyle
+	}
+}
+
+// Returns a copy of the styles with the fallback fonts added to the fonts of every part
+// of the diagram
+func (ds *DiagramStyles) withFallbackFonts() (*DiagramStyles, error) {
+	if len(ds.FallbackFonts) == 0 {
+		return ds, nil
+	}
+
+	fallbacks := make([]*graphbox.TTFFont, len(ds.FallbackFonts))
+	for i, fontFile := range ds.FallbackFonts {
+		font, err := LoadFontFile(fontFile)
+		if err != nil {
+			return nil, err
+		}
+		fallbacks[i] = font
+	}
+
+	styles := ds.Copy()
+	styles.mapFonts(func(font graphbox.Font) graphbox.Font {
+		if ttfFont, isTTF := font.(*graphbox.TTFFont); isTTF {
+			return ttfFont.WithFallbacks(fallbacks...)
+		}
+		return font
+	})
+	return styles, nil
+}
+
+// Returns a copy of the styles with the diagram colours applied to the styles of the
+// items which do not set their own colours.
+func (ds *DiagramStyles) withItemColors() *DiagramStyles {
+	styles := ds.Copy()
+	fg, knockout := ds.ForegroundColor, ds.KnockoutColor
+
+	styles.ActorBox.Color = colorOr(styles.ActorBox.Color, fg)
+	styles.ActorBox.TextColor = colorOr(styles.ActorBox.TextColor, fg)
+	styles.ActorBox.FillColor = colorOr(styles.ActorBox.FillColor, knockout)
+
+	styles.ActorIconBox.Color = colorOr(styles.ActorIconBox.Color, fg)
+	styles.ActorIconBox.TextColor = colorOr(styles.ActorIconBox.TextColor, fg)
+	styles.ActorIconBox.FillColor = colorOr(styles.ActorIconBox.FillColor, knockout)
+	styles.ActorIconBox.KnockoutColor = colorOr(styles.ActorIconBox.KnockoutColor, knockout)
+
+	styles.NoteBox.Color = colorOr(styles.NoteBox.Color, fg)
+	styles.NoteBox.TextColor = colorOr(styles.NoteBox.TextColor, fg)
+	styles.NoteBox.FillColor = colorOr(styles.NoteBox.FillColor, ds.NoteFillColor)
+
+	styles.ActivityLine.Color = colorOr(styles.ActivityLine.Color, fg)
+	styles.ActivityLine.KnockoutColor = colorOr(styles.ActivityLine.KnockoutColor, knockout)
+
+	styles.Title.Color = colorOr(styles.Title.Color, fg)
+	styles.Title.KnockoutColor = colorOr(styles.Title.KnockoutColor, knockout)
+
+	styles.Block.Color = colorOr(styles.Block.Color, ds.BlockColor)
+	styles.Block.TextColor = colorOr(styles.Block.TextColor, fg)
+	styles.Block.KnockoutColor = colorOr(styles.Block.KnockoutColor, knockout)
+
+	for dividerType, dividerStyle := range styles.Divider {
+		dividerStyle.Color = colorOr(dividerStyle.Color, fg)
+		dividerStyle.KnockoutColor = colorOr(dividerStyle.KnockoutColor, knockout)
+		styles.Divider[dividerType] = dividerStyle
+	}
+
+	styles.Duration.Color = colorOr(styles.Duration.Color, fg)
+
+	styles.Activation.Color = colorOr(styles.Activation.Color, fg)
+	styles.Activation.FillColor = colorOr(styles.Activation.FillColor, knockout)
+
+	return styles
 }
 
 // Fonts
@@ -140,6 +274,21 @@ var DefaultStyle = &DiagramStyles{
 			Shape:       graphbox.DSSpacerRect,
 		},
 	},
+	Duration: graphbox.DurationLineStyle{
+		Font:      standardFont,
+		FontSize:  12,
+		Margin:    graphbox.Point{8, 4},
+		TextGap:   6,
+		ArrowSize: 8,
+	},
+	Activation: graphbox.ActivationStyle{
+		Width: 10,
+	},
+	ForegroundColor: "black",
+	BackgroundColor: "white",
+	NoteFillColor:   "white",
+	BlockColor:      "black",
+	KnockoutColor:   "white",
 }
 
 // The Tight style.  Same horizontal dimensions as the normal
@@ -246,6 +395,21 @@ var TightStyle = &DiagramStyles{
 			Shape:       graphbox.DSSpacerRect,
 		},
 	},
+	Duration: graphbox.DurationLineStyle{
+		Font:      standardFont,
+		FontSize:  12,
+		Margin:    graphbox.Point{8, 2},
+		TextGap:   6,
+		ArrowSize: 8,
+	},
+	Activation: graphbox.ActivationStyle{
+		Width: 10,
+	},
+	ForegroundColor: "black",
+	BackgroundColor: "white",
+	NoteFillColor:   "white",
+	BlockColor:      "black",
+	KnockoutColor:   "white",
 }
 
 // The small style.  This has narrower margins and font sizes and
@@ -352,10 +516,40 @@ var SmallStyle = &DiagramStyles{
 			Shape:       graphbox.DSSpacerRect,
 		},
 	},
+	Duration: graphbox.DurationLineStyle{
+		Font:      standardFont,
+		FontSize:  10,
+		Margin:    graphbox.Point{4, 2},
+		TextGap:   4,
+		ArrowSize: 6,
+	},
+	Activation: graphbox.ActivationStyle{
+		Width: 8,
+	},
+	ForegroundColor: "black",
+	BackgroundColor: "white",
+	NoteFillColor:   "white",
+	BlockColor:      "black",
+	KnockoutColor:   "white",
+}
+
+// The dark style.  Same dimensions as the default style but with light lines and
+// text on a dark background.
+var DarkStyle = darkStyle()
+
+func darkStyle() *DiagramStyles {
+	styles := DefaultStyle.Copy()
+	styles.ForegroundColor = "#d4d4d4"
+	styles.BackgroundColor = "#1e1e1e"
+	styles.NoteFillColor = "#2d2d30"
+	styles.BlockColor = "#8c8c8c"
+	styles.KnockoutColor = "#1e1e1e"
+	return styles
 }
 
 var StyleNames = map[string]*DiagramStyles{
 	"default": DefaultStyle,
 	"tight":   TightStyle,
 	"small":   SmallStyle,
+	"dark":    DarkStyle,
 }
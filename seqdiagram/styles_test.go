@@ -0,0 +1,58 @@
+package seqdiagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+func TestDarkStyle(t *testing.T) {
+	assert := assert.Assert(t)
+
+	assert.Equal(StyleNames["dark"], DarkStyle)
+	assert.Equal(DarkStyle.ForegroundColor, "#d4d4d4")
+	assert.Equal(DarkStyle.BackgroundColor, "#1e1e1e")
+
+	// The dark style has the dimensions of the default style, which is left unchanged
+	assert.Equal(DarkStyle.Margin, DefaultStyle.Margin)
+	assert.Equal(DarkStyle.ActivityLine.FontSize, DefaultStyle.ActivityLine.FontSize)
+	assert.Equal(DefaultStyle.ForegroundColor, "black")
+	assert.Equal(DefaultStyle.BackgroundColor, "white")
+}
+
+func TestStylesWithItemColors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	base := DarkStyle.Copy()
+	base.Block.Color = "red"
+
+	styles := base.withItemColors()
+	assert.Equal(styles.ActorBox.Color, "#d4d4d4")
+	assert.Equal(styles.ActorBox.FillColor, "#1e1e1e")
+	assert.Equal(styles.NoteBox.FillColor, "#2d2d30")
+	assert.Equal(styles.ActivityLine.KnockoutColor, "#1e1e1e")
+	assert.Equal(styles.Divider[DTGap].Color, "#d4d4d4")
+	assert.Equal(styles.Duration.Color, "#d4d4d4")
+
+	// Colours set on the items are kept
+	assert.Equal(styles.Block.Color, "red")
+
+	// The styles the colours are applied to are not changed
+	assert.Equal(base.ActorBox.Color, "")
+	assert.Equal(DarkStyle.Divider[DTGap].Color, "")
+}
+
+func TestDarkStyleBackground(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Hi\n")
+
+	svg := renderSVG(t, diagram, &ImageOptions{Style: DarkStyle})
+	assert.Equal(strings.Contains(svg, `class="background"`), true)
+	assert.Equal(strings.Contains(svg, "fill:#1e1e1e"), true)
+	assert.Equal(strings.Contains(svg, "stroke:black"), false)
+
+	svg = renderSVG(t, diagram, &ImageOptions{Style: DarkStyle, Transparent: true})
+	assert.Equal(strings.Contains(svg, `class="background"`), false)
+}
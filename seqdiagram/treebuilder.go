@@ -4,6 +4,9 @@ package seqdiagram
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/lmika/goseq/seqdiagram/parse"
@@ -23,9 +26,11 @@ var arrowHeadMap = map[parse.ArrowHeadType]ArrowHead{
 }
 
 var noteAlignmentMap = map[parse.NoteAlignment]NoteAlignment{
-	parse.LEFT_NOTE_ALIGNMENT:  LeftNoteAlignment,
-	parse.RIGHT_NOTE_ALIGNMENT: RightNoteAlignment,
-	parse.OVER_NOTE_ALIGNMENT:  OverNoteAlignment,
+	parse.LEFT_NOTE_ALIGNMENT:    LeftNoteAlignment,
+	parse.RIGHT_NOTE_ALIGNMENT:   RightNoteAlignment,
+	parse.OVER_NOTE_ALIGNMENT:    OverNoteAlignment,
+	parse.ACROSS_NOTE_ALIGNMENT:  AcrossNoteAlignment,
+	parse.MESSAGE_NOTE_ALIGNMENT: MessageNoteAlignment,
 }
 
 var dividerTypeMap = map[parse.GapType]DividerType{
@@ -49,12 +54,35 @@ var segmentTypeMap = map[parse.SegmentType]SegmentType{
 // styleIdentifierParticipant is the style identifier for participants
 const styleIdentifierParticipant = "participant"
 
+// styleIdentifierNote is the style identifier for notes
+const styleIdentifierNote = "note"
+
+// messageNotePattern matches a note at the end of an action's message
+var messageNotePattern = regexp.MustCompile(`\s*\[note:\s*(.*?)\s*\]\s*$`)
+
 type treeBuilder struct {
 	nodeList *parse.NodeList
 	filename string
 
 	// List of style definitions
 	styleDefs map[string]*AttributeSet
+
+	// The last order assigned by a participants declaration
+	lastOrder int
+
+	// The calls which have not yet been returned, with the most recent call last
+	activeCalls []*Action
+
+	// The calls made with a body, which are drawn with activations once returned
+	bodyCalls map[*Action]bool
+
+	// The action of the most recently declared item, or nil if the item is not an action
+	lastAction *Action
+
+	// The participant which received the most recent action, which is the participant
+	// returning from a call with a return statement.  Calls without a body are not
+	// returned, so the caller remains the current participant
+	currentActor *Actor
 }
 
 func newTreeBuilder(nl *parse.NodeList, filename string) *treeBuilder {
@@ -62,20 +90,47 @@ func newTreeBuilder(nl *parse.NodeList, filename string) *treeBuilder {
 		nodeList:  nl,
 		filename:  filename,
 		styleDefs: make(map[string]*AttributeSet),
+		bodyCalls: make(map[*Action]bool),
 	}
 }
 
 func (tb *treeBuilder) buildTree(d *Diagram) error {
 
 	for nodeList := tb.nodeList; nodeList != nil; nodeList = nodeList.Tail {
-		seqItem, err := tb.toSequenceItem(nodeList.Head, d)
+		seqItems, err := tb.toSequenceItems(nodeList.Head, d)
 		if err != nil {
 			return err
-		} else if seqItem != nil {
+		}
+		for _, seqItem := range seqItems {
 			d.AddSequenceItem(seqItem)
 		}
 	}
 
+	d.SortActors()
+	return tb.checkDurations(d)
+}
+
+// Checks that the time points referenced by durations are defined
+func (tb *treeBuilder) checkDurations(d *Diagram) error {
+	timePoints := make(map[string]bool)
+	durations := make([]*Duration, 0)
+
+	walkItems(d.Items, func(item SequenceItem) {
+		switch itemDetails := item.(type) {
+		case *Action:
+			timePoints[itemDetails.TimePoint] = true
+		case *Duration:
+			durations = append(durations, itemDetails)
+		}
+	})
+
+	for _, duration := range durations {
+		for _, timePoint := range []string{duration.From, duration.To} {
+			if !timePoints[timePoint] {
+				return tb.makeError("Unknown time point: " + timePoint)
+			}
+		}
+	}
 	return nil
 }
 
@@ -83,17 +138,42 @@ func (tb *treeBuilder) nodesToSlice(nodeList *parse.NodeList, d *Diagram) ([]Seq
 	seq := make([]SequenceItem, 0)
 
 	for ; nodeList != nil; nodeList = nodeList.Tail {
-		seqItem, err := tb.toSequenceItem(nodeList.Head, d)
+		seqItems, err := tb.toSequenceItems(nodeList.Head, d)
 		if err != nil {
 			return nil, err
-		} else if seqItem != nil {
-			seq = append(seq, seqItem)
 		}
+		seq = append(seq, seqItems...)
 	}
 
 	return seq, nil
 }
 
+// Converts a node to the sequence items it declares.  Most nodes declare at most one item
+// but calls expand into the items of the call, its body and the return.
+func (tb *treeBuilder) toSequenceItems(node parse.Node, d *Diagram) ([]SequenceItem, error) {
+	var seqItems []SequenceItem
+
+	if callNode, isCall := node.(*parse.CallNode); isCall {
+		items, err := tb.addCall(callNode, d)
+		if err != nil {
+			return nil, err
+		}
+		seqItems = items
+	} else {
+		seqItem, err := tb.toSequenceItem(node, d)
+		if err != nil {
+			return nil, err
+		} else if seqItem != nil {
+			seqItems = []SequenceItem{seqItem}
+		}
+	}
+
+	if len(seqItems) > 0 {
+		tb.lastAction, _ = seqItems[len(seqItems)-1].(*Action)
+	}
+	return seqItems, nil
+}
+
 func (tb *treeBuilder) makeError(msg string) error {
 	return fmt.Errorf("%s:%s", tb.filename, msg)
 }
@@ -112,21 +192,31 @@ func (tb *treeBuilder) toSequenceItem(node parse.Node, d *Diagram) (SequenceItem
 	case *parse.ActorNode:
 		err := tb.addActor(n, d) // d.GetOrAddActorWithOptions(n.Ident, n.ActorName())
 		return nil, err
+	case *parse.ParticipantsNode:
+		tb.orderActors(n, d)
+		return nil, nil
 	case *parse.ActionNode:
 		return tb.addAction(n, d)
+	case *parse.ReturnNode:
+		return tb.addReturn(n)
 	case *parse.NoteNode:
 		return tb.addNote(n, d)
 	case *parse.GapNode:
 		return tb.addGap(n, d)
 	case *parse.BlockNode:
 		return tb.addBlock(n, d)
+	case *parse.ConditionalNode:
+		return tb.addConditional(n, d)
+	case *parse.LabelNode:
+		return &Label{n.Name}, nil
+	case *parse.PageBreakNode:
+		return &PageBreak{}, nil
+	case *parse.DurationNode:
+		return &Duration{n.From, n.To, n.Descr}, nil
+	case *parse.ViewNode:
+		return nil, tb.addView(n, d)
 	case *parse.StyleNode:
-		if attrs, err := tb.attrsToMap(n.Attributes, tb.styleDefs[n.Name]); err == nil {
-			tb.styleDefs[n.Name] = attrs
-			return nil, nil
-		} else {
-			return nil, err
-		}
+		return nil, tb.addStyle(n, d)
 	default:
 		return nil, tb.makeError("Unrecognised declaration")
 	}
@@ -143,6 +233,7 @@ func (tb *treeBuilder) addActor(an *parse.ActorNode, d *Diagram) error {
 
 	// Configure the attributes
 	if iconName, hasIconName := attrMap.Get("icon"); hasIconName && (iconName != "none") {
+		iconName = tb.resolveIconFile(iconName)
 		if icon, err := LookupActorIcon(iconName); err == nil {
 			actor.Icon = icon
 		} else {
@@ -150,49 +241,358 @@ func (tb *treeBuilder) addActor(an *parse.ActorNode, d *Diagram) error {
 		}
 	}
 
+	if orderStr, hasOrder := attrMap.Get("order"); hasOrder {
+		order, err := strconv.Atoi(orderStr)
+		if (err != nil) || (order < 1) {
+			return tb.makeError("Invalid order for participant " + an.Ident + ": " + orderStr)
+		}
+		actor.Order = order
+		tb.lastOrder = maxInt(tb.lastOrder, order)
+	}
+
+	if members, hasMembers := attrMap.Get("members"); hasMembers {
+		actor.Members = nil
+		for _, member := range SplitList(members) {
+			if member == an.Ident {
+				return tb.makeError("Participant " + an.Ident + " cannot be a member of itself")
+			}
+			actor.Members = append(actor.Members, d.GetOrAddActor(member))
+		}
+	}
+	actor.ElideInternalActions = attrMap.GetDef("internal", "self") == "elide"
+
 	actor.InHeader = attrMap.GetDef("header", "normal") != "none"
 	actor.InFooter = attrMap.GetDef("footer", "normal") != "none"
 	actor.Lifeline = attrMap.GetDef("lifeline", "dashed") != "none"
-	actor.Color = attrMap.GetDef("color", "black")
+	actor.Color = attrMap.GetDef("color", "")
 	actor.TextColor = attrMap.GetDef("textcolor", actor.Color)
+	actor.Stereotype = attrMap.GetDef("stereotype", "")
+
+	return nil
+}
+
+// Resolves the filename of icons loaded from files relative to the diagram file
+func (tb *treeBuilder) resolveIconFile(iconName string) string {
+	if !strings.HasPrefix(iconName, fileIconPrefix) {
+		return iconName
+	}
+
+	return fileIconPrefix + tb.resolveDiagramFile(strings.TrimPrefix(iconName, fileIconPrefix))
+}
+
+// Resolves a filename relative to the diagram file
+func (tb *treeBuilder) resolveDiagramFile(filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+	return filepath.Join(filepath.Dir(tb.filename), filename)
+}
+
+// Assigns explicit orders to the actors of a participants declaration.  The actors are
+// placed after any actors that have already been ordered.
+func (tb *treeBuilder) orderActors(pn *parse.ParticipantsNode, d *Diagram) {
+	for il := pn.Idents; il != nil; il = il.Tail {
+		tb.lastOrder++
+		d.GetOrAddActor(il.Head).Order = tb.lastOrder
+	}
+}
 
+func (tb *treeBuilder) addView(vn *parse.ViewNode, d *Diagram) error {
+	attrMap, err := tb.attrsToMap(vn.Attributes, nil)
+	if err != nil {
+		return err
+	}
+
+	if d.Views == nil {
+		d.Views = make(map[string]*View)
+	}
+	d.Views[vn.Name] = &View{
+		Name:         vn.Name,
+		HiddenActors: ParseHiddenActors(attrMap.GetDef("hide", "")),
+		Collapse:     SplitList(attrMap.GetDef("collapse", "")),
+	}
 	return nil
 }
 
 func (tb *treeBuilder) addAction(an *parse.ActionNode, d *Diagram) (SequenceItem, error) {
-	from, err := tb.getOrAddActor(an.From, d)
+	message, noteMessage := an.Descr, ""
+	if match := messageNotePattern.FindStringSubmatchIndex(message); match != nil {
+		message, noteMessage = message[:match[0]], message[match[2]:match[3]]
+	}
+
+	action, err := tb.buildAction(an.From, an.To, an.Arrow, message, an.Attributes, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if noteMessage != "" {
+		action.Note = &Note{Align: MessageNoteAlignment, Message: noteMessage}
+		if err := tb.setNoteAttributes(action.Note, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	tb.trackCall(action)
+	return action, nil
+}
+
+func (tb *treeBuilder) buildAction(fromRef, toRef parse.ActorRef, arrowType parse.ArrowType, message string,
+	attrs *parse.AttributeList, d *Diagram) (*Action, error) {
+
+	from, err := tb.getOrAddActor(fromRef, d)
 	if err != nil {
 		return nil, err
 	}
 
-	to, err := tb.getOrAddActor(an.To, d)
+	to, err := tb.getOrAddActor(toRef, d)
 	if err != nil {
 		return nil, err
 	}
 
-	arrow := Arrow{arrowStemMap[an.Arrow.Stem], arrowHeadMap[an.Arrow.Head]}
-	action := &Action{from, to, arrow, an.Descr}
-	return action, nil
+	attrMap, err := tb.attrsToMap(attrs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	arrow := Arrow{arrowStemMap[arrowType.Stem], arrowHeadMap[arrowType.Head]}
+	return &Action{
+		From:      from,
+		To:        to,
+		Arrow:     arrow,
+		Message:   message,
+		TimePoint: attrMap.GetDef("at", ""),
+	}, nil
 }
 
-func (tb *treeBuilder) addNote(nn *parse.NoteNode, d *Diagram) (SequenceItem, error) {
-	actor1, err := tb.getOrAddActor(nn.Actor1, d)
+// Expands a call into the action of the call, followed by the items of the body and
+// the return.  The return is added automatically unless the body returns from the
+// call itself.  Calls without a body are not returned.  The called actor is activated
+// until the call is returned.
+func (tb *treeBuilder) addCall(cn *parse.CallNode, d *Diagram) ([]SequenceItem, error) {
+	call, err := tb.buildAction(cn.From, cn.To, cn.Arrow, cn.Call, cn.Attributes, d)
+	if err != nil {
+		return nil, err
+	} else if !cn.HasBody {
+		tb.currentActor = call.From
+		return []SequenceItem{call}, nil
+	}
+
+	outerCalls := tb.activeCalls
+	tb.activeCalls = append(append([]*Action(nil), outerCalls...), call)
+	tb.bodyCalls[call] = true
+	tb.currentActor = call.To
+
+	body, err := tb.nodesToSlice(cn.SubNodes, d)
 	if err != nil {
 		return nil, err
 	}
 
-	var actor2 *Actor = nil
-	if nn.Actor2 != nil {
-		actor2, err = tb.getOrAddActor(nn.Actor2, d)
+	items := append([]SequenceItem{call}, body...)
+	if containsAction(tb.activeCalls, call) {
+		items = append(items, tb.returnCall(call, returnFrom(call, "")))
+	}
+	tb.activeCalls = outerCalls
+	tb.currentActor = call.From
+	return items, nil
+}
+
+// Tracks the calls which have not yet been returned.  A dashed action returns the most
+// recent call made in the opposite direction, along with any calls made after it.  All
+// other actions are treated as calls.
+func (tb *treeBuilder) trackCall(action *Action) {
+	tb.currentActor = action.To
+	if action.Arrow.Stem != DashedArrowStem {
+		tb.activeCalls = append(tb.activeCalls, action)
+		return
+	}
+
+	for i := len(tb.activeCalls) - 1; i >= 0; i-- {
+		call := tb.activeCalls[i]
+		if (call.From == action.To) && (call.To == action.From) {
+			tb.activeCalls = tb.activeCalls[:i]
+			tb.returnCall(call, action)
+			return
+		}
+	}
+}
+
+// Builds the items of one of several alternative sections, such as the segments of a
+// block.  Each alternative starts with the calls and current participant as they were
+// before the first alternative.  The calls active after the last alternative are kept.
+func (tb *treeBuilder) alternativeToSlice(nodeList *parse.NodeList, d *Diagram, activeCalls []*Action,
+	currentActor *Actor) ([]SequenceItem, error) {
+
+	tb.activeCalls = append([]*Action(nil), activeCalls...)
+	tb.currentActor = currentActor
+	tb.lastAction = nil
+	return tb.nodesToSlice(nodeList, d)
+}
+
+// Adds a dashed reply to the most recent call into the current participant which has
+// not been returned.  Any calls made after it are treated as returned.
+func (tb *treeBuilder) addReturn(rn *parse.ReturnNode) (SequenceItem, error) {
+	for i := len(tb.activeCalls) - 1; i >= 0; i-- {
+		call := tb.activeCalls[i]
+		if call.To == tb.currentActor {
+			tb.activeCalls = tb.activeCalls[:i]
+			tb.currentActor = call.From
+			return tb.returnCall(call, returnFrom(call, rn.Descr)), nil
+		}
+	}
+
+	if tb.currentActor == nil {
+		return nil, tb.makeError("Return without a call to return from")
+	}
+	return nil, tb.makeError("Return without a call into " + tb.currentActor.Name + " to return from")
+}
+
+// Records the action returning a call made with a body, so that the call can be drawn
+// with an activation.  Returns the returning action.
+func (tb *treeBuilder) returnCall(call *Action, ret *Action) *Action {
+	if tb.bodyCalls[call] {
+		call.Return = ret
+	}
+	return ret
+}
+
+// Returns true if the action is one of the actions
+func containsAction(actions []*Action, action *Action) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the dashed reply to a call
+func returnFrom(call *Action, message string) *Action {
+	return &Action{
+		From:    call.To,
+		To:      call.From,
+		Arrow:   Arrow{DashedArrowStem, call.Arrow.Head},
+		Message: message,
+	}
+}
+
+func (tb *treeBuilder) addNote(nn *parse.NoteNode, d *Diagram) (SequenceItem, error) {
+	note := &Note{Align: noteAlignmentMap[nn.Position], Message: nn.Descr}
+
+	if nn.Actor1 != nil {
+		actor1, err := tb.getOrAddActor(nn.Actor1, d)
 		if err != nil {
 			return nil, err
 		}
+		note.Actor1 = actor1
+
+		if nn.Actor2 != nil {
+			actor2, err := tb.getOrAddActor(nn.Actor2, d)
+			if err != nil {
+				return nil, err
+			}
+			note.Actor2 = actor2
+		}
 	}
 
-	note := &Note{actor1, actor2, noteAlignmentMap[nn.Position], nn.Descr}
+	if err := tb.setNoteAttributes(note, nn.Attributes); err != nil {
+		return nil, err
+	}
+
+	if note.Align == MessageNoteAlignment {
+		if tb.lastAction == nil {
+			return nil, tb.makeError("Note on message does not follow a message")
+		} else if tb.lastAction.Note != nil {
+			return nil, tb.makeError("Message already has a note: " + tb.lastAction.Message)
+		}
+		tb.lastAction.Note = note
+		return nil, nil
+	}
 	return note, nil
 }
 
+// Adds a style statement.  The attributes become the defaults of the items with the style
+// identifier.  For the identifiers with attributes which override the diagram styles,
+// these attributes are added to the diagram.
+func (tb *treeBuilder) addStyle(sn *parse.StyleNode, d *Diagram) error {
+	attrMap, err := tb.attrsToMap(sn.Attributes, tb.styleDefs[sn.Name])
+	if err != nil {
+		return err
+	}
+	tb.styleDefs[sn.Name] = attrMap
+
+	overrides, hasOverrides := styleOverrides[sn.Name]
+	if !hasOverrides {
+		return nil
+	}
+
+	for attrs := sn.Attributes; attrs != nil; attrs = attrs.Tail {
+		attr := attrs.Head
+		override, isOverride := overrides[attr.Name]
+		if !isOverride {
+			if (sn.Name == styleIdentifierNote) && noteAttributeNames[attr.Name] {
+				continue
+			}
+			return tb.makeError("Unrecognised " + sn.Name + " style attribute: " + attr.Name)
+		}
+
+		// Font files are relative to the diagram file
+		value := attr.Value
+		if attr.Name == "font" {
+			value = tb.resolveDiagramFile(value)
+		} else if attr.Name == "fallbackfonts" {
+			fontFiles := SplitList(value)
+			for i, fontFile := range fontFiles {
+				fontFiles[i] = tb.resolveDiagramFile(fontFile)
+			}
+			value = strings.Join(fontFiles, ",")
+		}
+
+		// Check the value now so that the error refers to the diagram
+		if err := override(&DiagramStyles{}, value); err != nil {
+			return tb.makeError(err.Error())
+		}
+
+		if d.StyleOverrides == nil {
+			d.StyleOverrides = make(map[string]map[string]string)
+		}
+		if d.StyleOverrides[sn.Name] == nil {
+			d.StyleOverrides[sn.Name] = make(map[string]string)
+		}
+		d.StyleOverrides[sn.Name][attr.Name] = value
+	}
+
+	return nil
+}
+
+// The attributes of notes, which can also be set with the note style
+var noteAttributeNames = map[string]bool{
+	"shape":     true,
+	"color":     true,
+	"fill":      true,
+	"textcolor": true,
+}
+
+// Sets the shape and colours of a note from the attributes and the note style
+func (tb *treeBuilder) setNoteAttributes(note *Note, attrs *parse.AttributeList) error {
+	attrMap, err := tb.attrsToMap(attrs, tb.styleDefs[styleIdentifierNote])
+	if err != nil {
+		return err
+	}
+
+	if shapeName, hasShape := attrMap.Get("shape"); hasShape {
+		shape, isShape := NoteShapeNames[shapeName]
+		if !isShape {
+			return tb.makeError("Unrecognised note shape: " + shapeName)
+		}
+		note.Shape = shape
+	}
+	note.Color = attrMap.GetDef("color", "")
+	note.FillColor = attrMap.GetDef("fill", "")
+	note.TextColor = attrMap.GetDef("textcolor", note.Color)
+
+	return nil
+}
+
 func (tb *treeBuilder) getOrAddActor(ar parse.ActorRef, d *Diagram) (*Actor, error) {
 	switch a := ar.(type) {
 	case parse.NormalActorRef:
@@ -205,7 +605,7 @@ func (tb *treeBuilder) getOrAddActor(ar parse.ActorRef, d *Diagram) (*Actor, err
 		case "right":
 			return RightOffsideActor, nil
 		default:
-			return nil, fmt.Errorf("Invalid pseudo actor: ", pn)
+			return nil, fmt.Errorf("Invalid pseudo actor: %s", pn)
 		}
 	default:
 		return nil, fmt.Errorf("Unknown actor reference")
@@ -218,9 +618,10 @@ func (tb *treeBuilder) addGap(gn *parse.GapNode, d *Diagram) (SequenceItem, erro
 }
 
 func (tb *treeBuilder) addBlock(bn *parse.BlockNode, d *Diagram) (SequenceItem, error) {
+	activeCalls, currentActor := tb.activeCalls, tb.currentActor
 	segs := make([]*BlockSegment, 0)
 	for sn := bn.Segments; sn != nil; sn = sn.Tail {
-		seg, err := tb.buildSegment(sn.Head, d)
+		seg, err := tb.buildSegment(sn.Head, d, activeCalls, currentActor)
 		if err != nil {
 			return nil, err
 		}
@@ -231,8 +632,10 @@ func (tb *treeBuilder) addBlock(bn *parse.BlockNode, d *Diagram) (SequenceItem,
 	return &Block{segs}, nil
 }
 
-func (tb *treeBuilder) buildSegment(sn *parse.BlockSegment, d *Diagram) (*BlockSegment, error) {
-	slice, err := tb.nodesToSlice(sn.SubNodes, d)
+func (tb *treeBuilder) buildSegment(sn *parse.BlockSegment, d *Diagram, activeCalls []*Action,
+	currentActor *Actor) (*BlockSegment, error) {
+
+	slice, err := tb.alternativeToSlice(sn.SubNodes, d, activeCalls, currentActor)
 	if err != nil {
 		return nil, err
 	}
@@ -245,6 +648,25 @@ func (tb *treeBuilder) buildSegment(sn *parse.BlockSegment, d *Diagram) (*BlockS
 	}, nil
 }
 
+func (tb *treeBuilder) addConditional(cn *parse.ConditionalNode, d *Diagram) (SequenceItem, error) {
+	if cn.Kind != "feature" {
+		return nil, tb.makeError("Unrecognised condition: " + cn.Kind)
+	}
+
+	activeCalls, currentActor := tb.activeCalls, tb.currentActor
+	items, err := tb.alternativeToSlice(cn.SubNodes, d, activeCalls, currentActor)
+	if err != nil {
+		return nil, err
+	}
+
+	elseItems, err := tb.alternativeToSlice(cn.ElseSubNodes, d, activeCalls, currentActor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conditional{cn.Arg, items, elseItems}, nil
+}
+
 func (tb *treeBuilder) attrsToMap(attrs *parse.AttributeList, parent *AttributeSet) (*AttributeSet, error) {
 	attrMaps := make(map[string]string)
 
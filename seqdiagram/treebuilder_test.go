@@ -0,0 +1,230 @@
+package seqdiagram
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+// Describes the actions of the items as "From->To: Message", with dashed arrows
+// written as "-->"
+func describeActions(items []SequenceItem) []string {
+	descrs := make([]string, 0)
+	walkItems(items, func(item SequenceItem) {
+		if action, isAction := item.(*Action); isAction {
+			arrow := "->"
+			if action.Arrow.Stem == DashedArrowStem {
+				arrow = "-->"
+			}
+			descrs = append(descrs, action.From.Name+arrow+action.To.Name+": "+action.Message)
+		}
+	})
+	return descrs
+}
+
+func TestCallReturnedAutomatically(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.authorize(token) {\n  B->C.lookup()\n}\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: authorize(token)",
+		"B->C: lookup()",
+		"B-->A: ",
+	})
+	assert.Equal(diagram.Items[0].(*Action).Return, diagram.Items[2].(*Action))
+}
+
+func TestCallReturnedExplicitlyBeforeAnotherCall(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.authorize(token) {\n  return ok\n  B->C: notify\n}\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: authorize(token)",
+		"B-->A: ok",
+		"B->C: notify",
+	})
+	assert.Equal(diagram.Items[0].(*Action).Return, diagram.Items[1].(*Action))
+}
+
+func TestCallWithOneLineBody(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.x() { B->C: query }\nA->B.y() { B->C: a \\} b }\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: x()",
+		"B->C: query",
+		"B-->A: ",
+		"A->B: y()",
+		"B->C: a } b",
+		"B-->A: ",
+	})
+}
+
+func TestNestedCallsReturnedInOrder(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B.outer() {\n  B->C.inner() {\n    return 1\n  }\n}\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: outer()",
+		"B->C: inner()",
+		"C-->B: 1",
+		"B-->A: ",
+	})
+	assert.Equal(diagram.Items[0].(*Action).Return, diagram.Items[3].(*Action))
+	assert.Equal(diagram.Items[1].(*Action).Return, diagram.Items[2].(*Action))
+}
+
+func TestReturnRepliesToCallIntoCurrentParticipant(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "Client->Server: Request\nServer->DB: Query\nreturn: Rows\n"+
+		"return: Response\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"Client->Server: Request",
+		"Server->DB: Query",
+		"DB-->Server: Rows",
+		"Server-->Client: Response",
+	})
+}
+
+func TestReturnSkipsUnrelatedMessages(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Request\nB->C: Notify\nC->D: Event\nD-->C: Ack\n"+
+		"C-->B: Ack\nreturn: Response\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: Request",
+		"B->C: Notify",
+		"C->D: Event",
+		"D-->C: Ack",
+		"C-->B: Ack",
+		"B-->A: Response",
+	})
+}
+
+func TestReturnWithinBlockStartsFromParticipantBeforeBlock(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Request\nalt: found\n  return: Found\nelse: not found\n"+
+		"  return: Missing\nend\n")
+
+	assert.Equal(describeActions(diagram.Items), []string{
+		"A->B: Request",
+		"B-->A: Found",
+		"B-->A: Missing",
+	})
+}
+
+func TestReturnWithoutCallIntoCurrentParticipant(t *testing.T) {
+	assert := assert.Assert(t)
+
+	for _, src := range []string{
+		"return: done\n",
+		"A->B: Request\nB-->A: Response\nreturn: done\n",
+		"A->B: Request\nB->C: Notify\nC-->B: Ack\nreturn: Response\nreturn: again\n",
+	} {
+		_, err := ParseDiagram(strings.NewReader(src), "test.seq")
+		assert.Equal(err != nil, true)
+	}
+}
+
+func TestParseDurationsBetweenTimePoints(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B (at=\"t1\"): Request\nB->A (at=\"t2\"): Response\n"+
+		"duration t1..t2: < 200ms\n")
+
+	assert.Equal(diagram.Items[0].(*Action).TimePoint, "t1")
+	assert.Equal(diagram.Items[1].(*Action).TimePoint, "t2")
+	assert.Equal(diagram.Items[2], &Duration{"t1", "t2", "< 200ms"})
+}
+
+func TestDurationWithUnknownTimePoint(t *testing.T) {
+	assert := assert.Assert(t)
+
+	_, err := ParseDiagram(strings.NewReader("A->B (at=\"t1\"): Request\nduration t1..t2: < 200ms\n"), "test.seq")
+	assert.Equal(err != nil && strings.Contains(err.Error(), "Unknown time point: t2"), true)
+}
+
+func TestNotesOverSeveralParticipants(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: Hi\nnote over A, B: Both\nnote left of B, A: Reversed\nnote across: All\n")
+
+	over := diagram.Items[1].(*Note)
+	assert.Equal(over.Align == OverNoteAlignment, true)
+	assert.Equal([]string{over.Actor1.Name, over.Actor2.Name}, []string{"A", "B"})
+
+	left := diagram.Items[2].(*Note)
+	assert.Equal(left.Align == LeftNoteAlignment, true)
+	assert.Equal([]string{left.Actor1.Name, left.Actor2.Name}, []string{"B", "A"})
+
+	across := diagram.Items[3].(*Note)
+	assert.Equal(across.Align == AcrossNoteAlignment, true)
+	assert.Equal(across.Actor1 == nil, true)
+}
+
+func TestNoteShapesAndColors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "style note (shape=\"folded\", fill=\"#ffe\")\nnote over A: Default\n"+
+		"note over A (shape=\"cloud\", color=\"navy\"): Cloud\n")
+
+	defaultNote := diagram.Items[0].(*Note)
+	assert.Equal(defaultNote.Shape == FoldedNoteShape, true)
+	assert.Equal([]string{defaultNote.Color, defaultNote.FillColor, defaultNote.TextColor}, []string{"", "#ffe", ""})
+
+	cloudNote := diagram.Items[1].(*Note)
+	assert.Equal(cloudNote.Shape == CloudNoteShape, true)
+	assert.Equal([]string{cloudNote.Color, cloudNote.FillColor, cloudNote.TextColor}, []string{"navy", "#ffe", "navy"})
+}
+
+func TestUnrecognisedNoteShape(t *testing.T) {
+	assert := assert.Assert(t)
+
+	_, err := ParseDiagram(strings.NewReader("note over A (shape=\"star\"): Star\n"), "test.seq")
+	assert.Equal(err != nil && strings.Contains(err.Error(), "Unrecognised note shape: star"), true)
+}
+
+func TestMessageNotes(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "style note (shape=\"rounded\")\nA->B: Create order [note: idempotent]\n"+
+		"B->C: Insert\nnote on message (fill=\"#ffe\"): Within a transaction\n")
+
+	assert.Equal(len(diagram.Items), 2)
+
+	create := diagram.Items[0].(*Action)
+	assert.Equal(create.Message, "Create order")
+	assert.Equal(create.Note.Message, "idempotent")
+	assert.Equal(create.Note.Align == MessageNoteAlignment, true)
+	assert.Equal(create.Note.Shape == RoundedNoteShape, true)
+
+	insert := diagram.Items[1].(*Action)
+	assert.Equal(insert.Message, "Insert")
+	assert.Equal(insert.Note.Message, "Within a transaction")
+	assert.Equal(insert.Note.FillColor, "#ffe")
+}
+
+func TestInvalidMessageNotes(t *testing.T) {
+	for _, test := range []struct {
+		src, message string
+	}{
+		{"note on message: Nothing to note\n", "Note on message does not follow a message"},
+		{"A->B: Hi\nnote over A: Between\nnote on message: Late\n", "Note on message does not follow a message"},
+		{"A->B: Hi [note: first]\nnote on message: second\n", "Message already has a note: Hi"},
+		{"A->B: Hi\nnote on message: first\nnote on message: second\n", "Message already has a note: Hi"},
+	} {
+		_, err := ParseDiagram(strings.NewReader(test.src), "test.seq")
+		if (err == nil) || !strings.Contains(err.Error(), test.message) {
+			t.Errorf("%q: expected error %q, got %v", test.src, test.message, err)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package seqdiagram
 
+import (
+	"sort"
+	"strings"
+)
+
 func maxInt(x int, y int) int {
 	if x > y {
 		return x
@@ -7,3 +12,49 @@ func maxInt(x int, y int) int {
 		return y
 	}
 }
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	} else {
+		return x
+	}
+}
+
+// Returns the colour, or the default colour if the colour is blank
+func colorOr(color string, defaultColor string) string {
+	if color == "" {
+		return defaultColor
+	}
+	return color
+}
+
+// Splits a comma separated list, trimming whitespace and ignoring empty items.  Used for
+// the values of attributes and command line flags.
+func SplitList(list string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Sorts the names of style identifiers or diagram parts, with "diagram" first so that
+// the settings of the whole diagram can be overridden by those of the other parts
+func sortDiagramFirst(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		return (names[i] == "diagram") || ((names[j] != "diagram") && (names[i] < names[j]))
+	})
+}
+
+// Returns the keys of a map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
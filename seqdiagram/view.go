@@ -0,0 +1,421 @@
+// Views of a diagram
+//
+
+package seqdiagram
+
+import (
+	"errors"
+)
+
+// Returns a view of the diagram to render with the given image options.  The view is
+// a shallow copy of the diagram containing only the sequence items and actors
+// which are to appear in the image.
+func (d *Diagram) viewFor(options *ImageOptions) (*Diagram, error) {
+	features := make(map[string]bool)
+	for _, feature := range options.Features {
+		features[feature] = true
+	}
+
+	hiddenActors, err := d.hiddenActorsFor(options)
+	if err != nil {
+		return nil, err
+	}
+
+	collapsedActors, err := d.collapsedActorsFor(options)
+	if err != nil {
+		return nil, err
+	}
+	for _, composite := range collapsedActors {
+		if _, isHidden := hiddenActors[composite]; isHidden {
+			return nil, errors.New("Participant cannot be both hidden and collapsed: " + composite.Name)
+		}
+	}
+
+	view := &Diagram{
+		ProcessingInstructions: d.ProcessingInstructions,
+		Title:                  d.Title,
+		Items:                  resolveConditionals(d.Items, features),
+		StyleOverrides:         d.StyleOverrides,
+	}
+
+	view.Items, err = selectRange(view.Items, options.FromLabel, options.ToLabel)
+	if err != nil {
+		return nil, err
+	}
+	view.Items = hideActors(view.Items, hiddenActors)
+	view.Items = collapseActors(view.Items, collapsedActors)
+	view.Actors = d.actorsInView(view.Items, hiddenActors, collapsedActors)
+
+	if options.AutoOrder {
+		view.Actors = autoOrderActors(view.Actors, view.Items)
+	}
+
+	return view, nil
+}
+
+// Returns the actors to hide, mapped to the actor their actions are to be rerouted
+// through (or nil if their actions are to be removed).  Returns an error if a hidden
+// actor is not part of the diagram.
+func (d *Diagram) hiddenActorsFor(options *ImageOptions) (map[*Actor]*Actor, error) {
+	hiddenActors := make(map[*Actor]*Actor)
+
+	addHiddenActors := func(names map[string]string) error {
+		for _, name := range sortedKeys(names) {
+			proxyName := names[name]
+			actor := d.findActor(name)
+			if actor == nil {
+				return errors.New("Unknown participant to hide: " + name)
+			}
+
+			var proxy *Actor
+			if proxyName != "" {
+				if proxy = d.findActor(proxyName); proxy == nil {
+					return errors.New("Unknown proxy participant: " + proxyName)
+				}
+			}
+			hiddenActors[actor] = proxy
+		}
+		return nil
+	}
+
+	if options.View != "" {
+		view, hasView := d.Views[options.View]
+		if !hasView {
+			return nil, errors.New("No such view: " + options.View)
+		}
+		if err := addHiddenActors(view.HiddenActors); err != nil {
+			return nil, err
+		}
+	}
+	if err := addHiddenActors(options.HiddenActors); err != nil {
+		return nil, err
+	}
+
+	// Follow proxies which are themselves hidden
+	for actor, proxy := range hiddenActors {
+		for hops := 0; (proxy != nil) && (hops < len(hiddenActors)); hops++ {
+			nextProxy, isHidden := hiddenActors[proxy]
+			if !isHidden {
+				break
+			}
+			proxy = nextProxy
+		}
+		if _, isHidden := hiddenActors[proxy]; isHidden {
+			proxy = nil
+		}
+		hiddenActors[actor] = proxy
+	}
+
+	return hiddenActors, nil
+}
+
+// Returns the actor with the given name, or nil if no such actor exists
+func (d *Diagram) findActor(name string) *Actor {
+	for _, actor := range d.Actors {
+		if actor.Name == name {
+			return actor
+		}
+	}
+	return nil
+}
+
+// Returns the members of the composite actors to collapse, mapped to their composite
+// actor.  Returns an error if a composite actor is not part of the diagram.
+func (d *Diagram) collapsedActorsFor(options *ImageOptions) (map[*Actor]*Actor, error) {
+	collapsedActors := make(map[*Actor]*Actor)
+
+	names := options.Collapse
+	if view, hasView := d.Views[options.View]; hasView {
+		names = append(append([]string(nil), view.Collapse...), names...)
+	}
+
+	for _, name := range names {
+		composite := d.findActor(name)
+		if composite == nil {
+			return nil, errors.New("Unknown participant to collapse: " + name)
+		} else if len(composite.Members) == 0 {
+			return nil, errors.New("Not a composite participant: " + name)
+		}
+
+		for _, member := range composite.Members {
+			collapsedActors[member] = composite
+		}
+	}
+	return collapsedActors, nil
+}
+
+// Returns the actors to include in a view containing the given items.  Actors which are
+// referenced by items of the original diagram but not by any item in the view are
+// excluded, as are hidden actors.  All other actors which are not referenced by any
+// items are included, except for composite actors.  A collapsed composite actor
+// appears in its own place, or in place of its first member if that is declared before
+// the composite actor.
+func (d *Diagram) actorsInView(viewItems []SequenceItem, hiddenActors map[*Actor]*Actor, collapsedActors map[*Actor]*Actor) []*Actor {
+	usedInDiagram := make(map[*Actor]bool)
+	usedInView := make(map[*Actor]bool)
+
+	collectActors(d.Items, usedInDiagram)
+	collectActors(viewItems, usedInView)
+
+	actors := make([]*Actor, 0, len(d.Actors))
+	added := make(map[*Actor]bool)
+	addActor := func(actor *Actor) {
+		if !added[actor] {
+			actors = append(actors, actor)
+			added[actor] = true
+		}
+	}
+
+	for _, actor := range d.Actors {
+		if _, isHidden := hiddenActors[actor]; isHidden {
+			continue
+		} else if composite, isCollapsed := collapsedActors[actor]; isCollapsed {
+			addActor(composite)
+		} else if usedInView[actor] {
+			addActor(actor)
+		} else if !usedInDiagram[actor] && (len(actor.Members) == 0) {
+			addActor(actor)
+		}
+	}
+	return actors
+}
+
+// Removes or reroutes the actions and notes involving hidden actors.  Actions to or from
+// a hidden actor are rerouted through its proxy, or removed if it has none.  Notes are
+// placed over the remaining visible actor, or removed if there are none.
+func hideActors(items []SequenceItem, hiddenActors map[*Actor]*Actor) []SequenceItem {
+	if len(hiddenActors) == 0 {
+		return items
+	}
+
+	return remapActors(items, func(actor *Actor) *Actor {
+		if proxy, isHidden := hiddenActors[actor]; isHidden {
+			return proxy
+		}
+		return actor
+	}, nil)
+}
+
+// Replaces the members of collapsed composite actors with the composite actor.  Actions
+// between members of the same composite actor are drawn as self references, or removed
+// if the composite actor elides internal actions.
+func collapseActors(items []SequenceItem, collapsedActors map[*Actor]*Actor) []SequenceItem {
+	if len(collapsedActors) == 0 {
+		return items
+	}
+
+	return remapActors(items, func(actor *Actor) *Actor {
+		if composite, isCollapsed := collapsedActors[actor]; isCollapsed {
+			return composite
+		}
+		return actor
+	}, func(original, mapped *Action) bool {
+		_, fromCollapsed := collapsedActors[original.From]
+		_, toCollapsed := collapsedActors[original.To]
+		isInternal := (mapped.From == mapped.To) && (fromCollapsed || toCollapsed)
+
+		return !(isInternal && mapped.From.ElideInternalActions)
+	})
+}
+
+// Rewrites the actors of the actions and notes within a list of items.  The mapping
+// returns the actor to use in place of another, or nil if the actor is to be removed.
+// Actions involving removed actors are dropped, as are actions for which keepAction
+// returns false.  Notes are kept as long as one of their actors remains.  The returns of
+// the copied actions refer to the copies of the returns, or nil if they are dropped.
+func remapActors(items []SequenceItem, mapActor func(actor *Actor) *Actor, keepAction func(original, mapped *Action) bool) []SequenceItem {
+	mappedActions := make(map[*Action]*Action)
+	mappedItems := remapItems(items, mapActor, keepAction, mappedActions)
+
+	walkItems(mappedItems, func(item SequenceItem) {
+		if action, isAction := item.(*Action); isAction && (action.Return != nil) {
+			action.Return = mappedActions[action.Return]
+		}
+	})
+	return mappedItems
+}
+
+// Rewrites the actors of a list of items, recording the copies of the actions which are kept
+func remapItems(items []SequenceItem, mapActor func(actor *Actor) *Actor, keepAction func(original, mapped *Action) bool, mappedActions map[*Action]*Action) []SequenceItem {
+	mappedItems := make([]SequenceItem, 0, len(items))
+	for _, item := range items {
+		switch itemDetails := item.(type) {
+		case *Action:
+			action := *itemDetails
+			action.From, action.To = mapActor(itemDetails.From), mapActor(itemDetails.To)
+			if (action.From == nil) || (action.To == nil) {
+				continue
+			} else if (keepAction != nil) && !keepAction(itemDetails, &action) {
+				continue
+			}
+			mappedActions[itemDetails] = &action
+			mappedItems = append(mappedItems, &action)
+		case *Note:
+			if itemDetails.Align == AcrossNoteAlignment {
+				mappedItems = append(mappedItems, item)
+				continue
+			}
+
+			note := *itemDetails
+			note.Actor1 = mapActor(itemDetails.Actor1)
+			if itemDetails.Actor2 != nil {
+				note.Actor2 = mapActor(itemDetails.Actor2)
+			}
+			if note.Actor1 == nil {
+				note.Actor1, note.Actor2 = note.Actor2, nil
+			}
+			if note.Actor1 == nil {
+				continue
+			} else if note.Actor2 == note.Actor1 {
+				note.Actor2 = nil
+			}
+			mappedItems = append(mappedItems, &note)
+		case *Block:
+			block := &Block{Segments: make([]*BlockSegment, len(itemDetails.Segments))}
+			for i, seg := range itemDetails.Segments {
+				newSeg := *seg
+				newSeg.SubItems = remapItems(seg.SubItems, mapActor, keepAction, mappedActions)
+				block.Segments[i] = &newSeg
+			}
+			mappedItems = append(mappedItems, block)
+		default:
+			mappedItems = append(mappedItems, item)
+		}
+	}
+
+	return mappedItems
+}
+
+// Returns the items between the from and to labels.  Blocks which contain items within the
+// range are kept, with only those segments which contain items within the range.  The
+// labels themselves are always removed.  Returns an error if a label does not exist, or
+// if the to label comes before the from label.
+func selectRange(items []SequenceItem, fromLabel string, toLabel string) ([]SequenceItem, error) {
+	labels := make(map[string]int)
+	walkItems(items, func(item SequenceItem) {
+		if label, isLabel := item.(*Label); isLabel {
+			if _, hasLabel := labels[label.Name]; !hasLabel {
+				labels[label.Name] = len(labels)
+			}
+		}
+	})
+
+	fromIndex, hasFrom := labels[fromLabel]
+	toIndex, hasTo := labels[toLabel]
+	if (fromLabel != "") && !hasFrom {
+		return nil, errors.New("No such label: " + fromLabel)
+	} else if (toLabel != "") && !hasTo {
+		return nil, errors.New("No such label: " + toLabel)
+	} else if (fromLabel != "") && (toLabel != "") && (toIndex <= fromIndex) {
+		return nil, errors.New("Label " + toLabel + " does not come after label " + fromLabel)
+	}
+
+	inRange := fromLabel == ""
+	return itemsInRange(items, fromLabel, toLabel, &inRange), nil
+}
+
+// Returns the items which are within the range.  The in range flag is updated as
+// labels are encountered.
+func itemsInRange(items []SequenceItem, fromLabel string, toLabel string, inRange *bool) []SequenceItem {
+	rangeItems := make([]SequenceItem, 0, len(items))
+
+	for _, item := range items {
+		switch itemDetails := item.(type) {
+		case *Label:
+			if itemDetails.Name == fromLabel {
+				*inRange = true
+			} else if itemDetails.Name == toLabel {
+				*inRange = false
+			}
+		case *Block:
+			block := &Block{Segments: make([]*BlockSegment, 0, len(itemDetails.Segments))}
+			for _, seg := range itemDetails.Segments {
+				wasInRange := *inRange
+				subItems := itemsInRange(seg.SubItems, fromLabel, toLabel, inRange)
+				if wasInRange || (len(subItems) > 0) {
+					newSeg := *seg
+					newSeg.SubItems = subItems
+					block.Segments = append(block.Segments, &newSeg)
+				}
+			}
+			if len(block.Segments) > 0 {
+				// The first segment determines the type of block
+				if block.Segments[0].Type != itemDetails.Segments[0].Type {
+					block.Segments[0].Type = itemDetails.Segments[0].Type
+				}
+				rangeItems = append(rangeItems, block)
+			}
+		default:
+			if *inRange {
+				rangeItems = append(rangeItems, item)
+			}
+		}
+	}
+
+	return rangeItems
+}
+
+// Replaces the conditional sections with either their items or else items depending
+// on whether the feature is enabled.  Blocks are copied with their segments resolved.
+func resolveConditionals(items []SequenceItem, features map[string]bool) []SequenceItem {
+	resolved := make([]SequenceItem, 0, len(items))
+
+	for _, item := range items {
+		switch itemDetails := item.(type) {
+		case *Conditional:
+			if features[itemDetails.Feature] {
+				resolved = append(resolved, resolveConditionals(itemDetails.Items, features)...)
+			} else {
+				resolved = append(resolved, resolveConditionals(itemDetails.ElseItems, features)...)
+			}
+		case *Block:
+			block := &Block{Segments: make([]*BlockSegment, len(itemDetails.Segments))}
+			for i, seg := range itemDetails.Segments {
+				newSeg := *seg
+				newSeg.SubItems = resolveConditionals(seg.SubItems, features)
+				block.Segments[i] = &newSeg
+			}
+			resolved = append(resolved, block)
+		default:
+			resolved = append(resolved, item)
+		}
+	}
+
+	return resolved
+}
+
+// Adds the actors referenced by the items (and any nested items) to the set
+func collectActors(items []SequenceItem, actors map[*Actor]bool) {
+	walkItems(items, func(item SequenceItem) {
+		switch itemDetails := item.(type) {
+		case *Action:
+			actors[itemDetails.From] = true
+			actors[itemDetails.To] = true
+		case *Note:
+			if itemDetails.Actor1 != nil {
+				actors[itemDetails.Actor1] = true
+			}
+			if itemDetails.Actor2 != nil {
+				actors[itemDetails.Actor2] = true
+			}
+		}
+	})
+}
+
+// Calls the function for each item, including the items nested within blocks and
+// conditional sections.  Nested items are visited after the item containing them.
+func walkItems(items []SequenceItem, fn func(item SequenceItem)) {
+	for _, item := range items {
+		fn(item)
+
+		switch itemDetails := item.(type) {
+		case *Block:
+			for _, seg := range itemDetails.Segments {
+				walkItems(seg.SubItems, fn)
+			}
+		case *Conditional:
+			walkItems(itemDetails.Items, fn)
+			walkItems(itemDetails.ElseItems, fn)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package seqdiagram
+
+import (
+	"testing"
+
+	"github.com/seanpont/assert"
+)
+
+func TestHideActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: 1\nB->C: 2\n")
+
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{HiddenActors: ParseHiddenActors("C")}), []string{"A", "B"})
+}
+
+func TestHideUnknownActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: 1\nB->C: 2\nview typo (hide=\"D\")\n")
+
+	_, err := diagram.viewFor(&ImageOptions{HiddenActors: ParseHiddenActors("Bee")})
+	assert.Equal(errString(err), "Unknown participant to hide: Bee")
+
+	_, err = diagram.viewFor(&ImageOptions{View: "typo"})
+	assert.Equal(errString(err), "Unknown participant to hide: D")
+}
+
+// Returns the message of an error, or a blank string if there is no error
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestCollapseUnknownActors(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant Backend (members=\"API, DB\")\nA->API: 1\nAPI->DB: 2\n")
+
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{Collapse: []string{"Backend"}}), []string{"Backend", "A"})
+
+	_, err := diagram.viewFor(&ImageOptions{Collapse: []string{"Frontend"}})
+	assert.Equal(errString(err), "Unknown participant to collapse: Frontend")
+
+	_, err = diagram.viewFor(&ImageOptions{Collapse: []string{"A"}})
+	assert.Equal(errString(err), "Not a composite participant: A")
+}
+
+func TestCollapsedActorPositions(t *testing.T) {
+	assert := assert.Assert(t)
+
+	// A composite declared after its members takes the place of its first member
+	diagram := mustParseDiagram(t, "A->API: 1\nAPI->DB: 2\nDB->C: 3\nparticipant Backend (members=\"API, DB\")\n")
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{Collapse: []string{"Backend"}}), []string{"A", "Backend", "C"})
+
+	// A composite declared before its members keeps its own place
+	diagram = mustParseDiagram(t, "participant A\nparticipant C\nparticipant Backend (members=\"API, DB\")\n"+
+		"A->API: 1\nAPI->DB: 2\nDB->C: 3\n")
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{Collapse: []string{"Backend"}}), []string{"A", "C", "Backend"})
+}
+
+func TestHideAndCollapseSameActor(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "participant Backend (members=\"API, DB\")\nA->API: 1\nAPI->DB: 2\n"+
+		"view ops (hide=\"Backend\")\n")
+
+	_, err := diagram.viewFor(&ImageOptions{Collapse: []string{"Backend"}, HiddenActors: map[string]string{"Backend": ""}})
+	assert.Equal(errString(err), "Participant cannot be both hidden and collapsed: Backend")
+
+	_, err = diagram.viewFor(&ImageOptions{View: "ops", Collapse: []string{"Backend"}})
+	assert.Equal(errString(err), "Participant cannot be both hidden and collapsed: Backend")
+
+	// Hiding a member of a collapsed composite is allowed
+	assert.Equal(viewActorNames(t, diagram, &ImageOptions{Collapse: []string{"Backend"}, HiddenActors: map[string]string{"DB": ""}}),
+		[]string{"Backend", "A"})
+}
+
+// Returns the messages of the actions of the range of items between two labels
+func rangeMessages(t *testing.T, diagram *Diagram, fromLabel string, toLabel string) []string {
+	items, err := selectRange(diagram.Items, fromLabel, toLabel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := make([]string, 0)
+	walkItems(items, func(item SequenceItem) {
+		if action, isAction := item.(*Action); isAction {
+			messages = append(messages, action.Message)
+		}
+	})
+	return messages
+}
+
+func TestSelectRange(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "A->B: 1\nlabel start\nA->B: 2\nloop: retry\n  B->C: 3\n  label done\n  B->C: 4\nend\nA->B: 5\n")
+
+	assert.Equal(rangeMessages(t, diagram, "", ""), []string{"1", "2", "3", "4", "5"})
+	assert.Equal(rangeMessages(t, diagram, "start", ""), []string{"2", "3", "4", "5"})
+	assert.Equal(rangeMessages(t, diagram, "", "start"), []string{"1"})
+	assert.Equal(rangeMessages(t, diagram, "start", "done"), []string{"2", "3"})
+	assert.Equal(rangeMessages(t, diagram, "done", ""), []string{"4", "5"})
+}
+
+func TestSelectRangeInvalidLabels(t *testing.T) {
+	assert := assert.Assert(t)
+
+	diagram := mustParseDiagram(t, "label start\nA->B: 1\nlabel done\nA->B: 2\n")
+
+	_, err := selectRange(diagram.Items, "begin", "done")
+	assert.Equal(errString(err), "No such label: begin")
+
+	_, err = selectRange(diagram.Items, "start", "finish")
+	assert.Equal(errString(err), "No such label: finish")
+
+	_, err = selectRange(diagram.Items, "done", "start")
+	assert.Equal(errString(err), "Label start does not come after label done")
+
+	_, err = selectRange(diagram.Items, "start", "start")
+	assert.Equal(errString(err), "Label start does not come after label start")
+}